@@ -0,0 +1,226 @@
+// Package barcode draws EAN-13 barcodes, the symbology ISBNs are printed
+// in, as SVG and PNG images.
+package barcode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Geometry of a symbol, in modules, the width of the narrowest bar. The
+// quiet zones leave room for the first digit on the left.
+const (
+	quietLeft   = 11
+	quietRight  = 7
+	symbolWidth = 95
+	top         = 4
+	barHeight   = 60
+	guardHeight = 65
+	textTop     = top + barHeight + 2
+
+	// Width and Height are the size of a whole image in modules.
+	Width  = quietLeft + symbolWidth + quietRight
+	Height = textTop + glyphHeight + 3
+)
+
+var (
+	// leftCodes are the odd parity (L) patterns of the digits. The even
+	// parity (G) and right hand (R) patterns are derived from them.
+	leftCodes = [10]string{
+		"0001101", "0011001", "0010011", "0111101", "0100011",
+		"0110001", "0101111", "0111011", "0110111", "0001011",
+	}
+	// parities tells, for each first digit, which of the left hand digits
+	// use even parity. The first digit itself isn't drawn as bars.
+	parities = [10]string{
+		"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG",
+		"LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL",
+	}
+
+	errLength   = errors.New("EAN-13 has to have 13 digits")
+	errChecksum = errors.New("EAN-13 has an invalid check digit")
+)
+
+// EAN13 is a validated EAN-13 code with its bars.
+type EAN13 struct {
+	code    string
+	modules [symbolWidth]bool
+}
+
+// NewEAN13 checks a 13 digit code and works out its bars.
+func NewEAN13(code string) (EAN13, error) {
+	if len(code) != 13 {
+		return EAN13{}, errLength
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return EAN13{}, errLength
+		}
+	}
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(code[i]-'0')
+	}
+	if (10-sum%10)%10 != int(code[12]-'0') {
+		return EAN13{}, errChecksum
+	}
+
+	e := EAN13{code: code}
+	pattern := "101"
+	parity := parities[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		digit := code[i] - '0'
+		if parity[i-1] == 'G' {
+			pattern += reverse(invert(leftCodes[digit]))
+		} else {
+			pattern += leftCodes[digit]
+		}
+	}
+	pattern += "01010"
+	for i := 7; i <= 12; i++ {
+		pattern += invert(leftCodes[code[i]-'0'])
+	}
+	pattern += "101"
+	for i, r := range pattern {
+		e.modules[i] = r == '1'
+	}
+	return e, nil
+}
+
+func (e EAN13) String() string {
+	return e.code
+}
+
+// isGuard tells whether a module belongs to the start, center or end guard,
+// whose bars run down between the digits.
+func isGuard(module int) bool {
+	return module < 3 || (module >= 45 && module < 50) || module >= symbolWidth-3
+}
+
+// bar is a run of dark modules, in image coordinates.
+type bar struct {
+	x, width, height int
+}
+
+func (e EAN13) bars() []bar {
+	var bars []bar
+	for i := 0; i < symbolWidth; i++ {
+		if !e.modules[i] {
+			continue
+		}
+		height := barHeight
+		if isGuard(i) {
+			height = guardHeight
+		}
+		last := len(bars) - 1
+		if last >= 0 && bars[last].x+bars[last].width == quietLeft+i && bars[last].height == height {
+			bars[last].width++
+			continue
+		}
+		bars = append(bars, bar{x: quietLeft + i, width: 1, height: height})
+	}
+	return bars
+}
+
+// digitSlots returns the left edge of the 7 module wide slot each digit is
+// printed in: the first digit in the left quiet zone, then two groups of
+// six under the halves of the symbol.
+func digitSlots() [13]int {
+	var slots [13]int
+	slots[0] = quietLeft - 8
+	for i := 0; i < 6; i++ {
+		slots[1+i] = quietLeft + 3 + 7*i
+		slots[7+i] = quietLeft + 50 + 7*i
+	}
+	return slots
+}
+
+// WriteSVG writes the barcode as a standalone SVG document drawn at scale
+// pixels per module.
+func (e EAN13) WriteSVG(w io.Writer, scale int) error {
+	buffered := bufio.NewWriter(w)
+	fmt.Fprintf(buffered, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		Width*scale, Height*scale, Width, Height)
+	fmt.Fprintf(buffered, `<rect width="%d" height="%d" fill="#fff"/>`, Width, Height)
+	e.writeSVGSymbol(buffered)
+	buffered.WriteString("</svg>\n")
+	return buffered.Flush()
+}
+
+// writeSVGSymbol writes the bars and digits in module units, so the caller
+// can place and scale them.
+func (e EAN13) writeSVGSymbol(w *bufio.Writer) {
+	w.WriteString(`<g fill="#000">`)
+	for _, b := range e.bars() {
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d"/>`, b.x, top, b.width, b.height)
+	}
+	w.WriteString(`</g><g font-family="monospace" font-size="9" text-anchor="middle">`)
+	for i, slot := range digitSlots() {
+		fmt.Fprintf(w, `<text x="%g" y="%d">%c</text>`, float64(slot)+3.5, textTop+glyphHeight, e.code[i])
+	}
+	w.WriteString(`</g>`)
+}
+
+// Image draws the barcode at scale pixels per module, with the digits in a
+// built-in bitmap font.
+func (e EAN13) Image(scale int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, Width*scale, Height*scale))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	fill := func(x, y, width, height int) {
+		for py := y * scale; py < (y+height)*scale; py++ {
+			for px := x * scale; px < (x+width)*scale; px++ {
+				img.SetGray(px, py, color.Gray{})
+			}
+		}
+	}
+	for _, b := range e.bars() {
+		fill(b.x, top, b.width, b.height)
+	}
+	for i, slot := range digitSlots() {
+		glyph := glyphs[e.code[i]-'0']
+		for y, row := range glyph {
+			for x, r := range row {
+				if r == '#' {
+					fill(slot+1+x, textTop+y, 1, 1)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes the barcode as a PNG image at scale pixels per module.
+func (e EAN13) WritePNG(w io.Writer, scale int) error {
+	return png.Encode(w, e.Image(scale))
+}
+
+func invert(pattern string) string {
+	inverted := []byte(pattern)
+	for i, b := range inverted {
+		if b == '0' {
+			inverted[i] = '1'
+		} else {
+			inverted[i] = '0'
+		}
+	}
+	return string(inverted)
+}
+
+func reverse(pattern string) string {
+	reversed := []byte(pattern)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	return string(reversed)
+}
@@ -0,0 +1,106 @@
+package barcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEAN13(t *testing.T) {
+	tests := []struct {
+		name            string
+		code            string
+		expectedModules string
+		expectedError   string
+	}{
+		{
+			name: "Ok",
+			code: "5901234123457",
+			expectedModules: "101" +
+				"0001011" + "0100111" + "0110011" + "0010011" + "0111101" + "0011101" +
+				"01010" +
+				"1100110" + "1101100" + "1000010" + "1011100" + "1001110" + "1000100" +
+				"101",
+		},
+		{name: "Wrong check digit", code: "5901234123458", expectedError: "EAN-13 has an invalid check digit"},
+		{name: "Too short", code: "590123412345", expectedError: "EAN-13 has to have 13 digits"},
+		{name: "Not digits", code: "59012341234x7", expectedError: "EAN-13 has to have 13 digits"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := NewEAN13(test.code)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			var modules strings.Builder
+			for _, dark := range code.modules {
+				if dark {
+					modules.WriteByte('1')
+				} else {
+					modules.WriteByte('0')
+				}
+			}
+			assert.Equal(t, test.expectedModules, modules.String())
+		})
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	code, err := NewEAN13("9780306406157")
+	assert.NoError(t, err)
+
+	var output bytes.Buffer
+	assert.NoError(t, code.WriteSVG(&output, 2))
+	svg := output.String()
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="226" height="152" viewBox="0 0 113 76">`))
+	assert.Contains(t, svg, `<rect x="11" y="4" width="1" height="65"/>`)
+	assert.Contains(t, svg, `<text x="6.5" y="73">9</text>`)
+	assert.Equal(t, 13, strings.Count(svg, "<text"))
+}
+
+func TestWritePNG(t *testing.T) {
+	code, err := NewEAN13("9780306406157")
+	assert.NoError(t, err)
+
+	var output bytes.Buffer
+	assert.NoError(t, code.WritePNG(&output, 3))
+	img, err := png.Decode(&output)
+	assert.NoError(t, err)
+	assert.Equal(t, Width*3, img.Bounds().Dx())
+	assert.Equal(t, Height*3, img.Bounds().Dy())
+
+	dark := func(module, y int) bool {
+		r, _, _, _ := img.At(module*3+1, y*3+1).RGBA()
+		return r == 0
+	}
+	// The start guard is bar, space, bar and runs below the other bars.
+	assert.True(t, dark(quietLeft, top+guardHeight-1))
+	assert.False(t, dark(quietLeft+1, top))
+	assert.True(t, dark(quietLeft+2, top))
+	assert.False(t, dark(quietLeft-1, top))
+}
+
+func TestWriteLabelSheet(t *testing.T) {
+	code, err := NewEAN13("9780306406157")
+	assert.NoError(t, err)
+
+	var output bytes.Buffer
+	labels := []Label{
+		{Title: "Pride & Prejudice", Subtitle: "9.5", Code: code},
+		{Title: strings.Repeat("a", 40), Code: code},
+	}
+	assert.NoError(t, WriteLabelSheet(&output, labels))
+	sheet := output.String()
+	assert.Contains(t, sheet, `<g transform="translate(0 0.5)" font-family="sans-serif"><text x="4" y="6" font-size="3.5">Pride &amp; Prejudice</text>`)
+	assert.Contains(t, sheet, `<g transform="translate(70 0.5)"`)
+	assert.Contains(t, sheet, strings.Repeat("a", 33)+"…</text>")
+
+	err = WriteLabelSheet(&output, make([]Label, LabelsPerSheet+1))
+	assert.EqualError(t, err, "a sheet takes at most 24 labels")
+}
@@ -0,0 +1,72 @@
+package barcode
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// A sheet is an A4 page of 3 by 8 labels of 70 by 37 mm, a common
+// self-adhesive label layout. Sizes are in millimetres.
+const (
+	sheetWidth   = 210
+	sheetHeight  = 297
+	labelColumns = 3
+	labelRows    = 8
+	labelWidth   = 70
+	labelHeight  = 37
+	// moduleSize is 90% of the nominal 0.33 mm, which scanners still read.
+	moduleSize = 0.3
+	// maxTitle keeps a title on one line of a label.
+	maxTitle = 34
+
+	// LabelsPerSheet is the most labels WriteLabelSheet takes.
+	LabelsPerSheet = labelColumns * labelRows
+)
+
+// Label is printed with its barcode below a title and a subtitle.
+type Label struct {
+	Title    string
+	Subtitle string
+	Code     EAN13
+}
+
+// WriteLabelSheet writes an SVG page of labels, filled row by row.
+func WriteLabelSheet(w io.Writer, labels []Label) error {
+	if len(labels) > LabelsPerSheet {
+		return fmt.Errorf("a sheet takes at most %d labels", LabelsPerSheet)
+	}
+	buffered := bufio.NewWriter(w)
+	fmt.Fprintf(buffered, `<svg xmlns="http://www.w3.org/2000/svg" width="%dmm" height="%dmm" viewBox="0 0 %d %d">`,
+		sheetWidth, sheetHeight, sheetWidth, sheetHeight)
+	marginX := float64(sheetWidth-labelColumns*labelWidth) / 2
+	marginY := float64(sheetHeight-labelRows*labelHeight) / 2
+	for i, label := range labels {
+		x := marginX + float64(i%labelColumns*labelWidth)
+		y := marginY + float64(i/labelColumns*labelHeight)
+		fmt.Fprintf(buffered, `<g transform="translate(%g %g)" font-family="sans-serif">`, x, y)
+		fmt.Fprintf(buffered, `<text x="4" y="6" font-size="3.5">`)
+		_ = xml.EscapeText(buffered, []byte(truncate(label.Title, maxTitle)))
+		fmt.Fprintf(buffered, `</text><text x="4" y="10.5" font-size="3">`)
+		_ = xml.EscapeText(buffered, []byte(label.Subtitle))
+		buffered.WriteString(`</text>`)
+		fmt.Fprintf(buffered, `<g transform="translate(%g 12) scale(%g)">`,
+			(labelWidth-Width*moduleSize)/2, moduleSize)
+		label.Code.writeSVGSymbol(buffered)
+		buffered.WriteString(`</g></g>`)
+	}
+	buffered.WriteString("</svg>\n")
+	return buffered.Flush()
+}
+
+// truncate shortens s to at most max characters, ending it with an ellipsis
+// if anything was cut.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,238 @@
+// Package catalog reads and writes book catalogs in the flat file formats
+// merchandisers keep them in.
+package catalog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// FormatOf returns the format of a media type, or an empty string if it
+// isn't a catalog format.
+func FormatOf(mediaType string) string {
+	switch mediaType {
+	case ContentTypeCSV:
+		return FormatCSV
+	case ContentTypeNDJSON, "application/ndjson":
+		return FormatNDJSON
+	default:
+		return ""
+	}
+}
+
+// maxLineSize bounds a single NDJSON line.
+const maxLineSize = 1 << 20
+
+// ErrMalformed is wrapped by the errors of a catalog that can't be read at
+// all, as opposed to a single invalid row.
+var ErrMalformed = errors.New("malformed catalog")
+
+// Record is one row of a catalog. Genre is the genre's name as written in
+// the file, Book.Genre is left for the caller to resolve. Err is set when
+// the row couldn't be read, as models.ValidationErrors for invalid fields.
+type Record struct {
+	Line  int
+	Book  models.Book
+	Genre string
+	Err   error
+}
+
+// Reader streams the records of a catalog. Read returns io.EOF after the
+// last record. Any other error means the file as a whole can't be read.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// NewReader returns a Reader for the given format.
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r), nil
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	default:
+		return nil, fmt.Errorf("format has to be one of %s, %s", FormatCSV, FormatNDJSON)
+	}
+}
+
+// columns can appear in a CSV header in any order. id is accepted so that an
+// export can be imported again, but ignored. isbn is optional.
+var columns = []string{"id", "name", "genre", "price", "amount", "isbn"}
+
+type csvReader struct {
+	csv *csv.Reader
+	// index maps a column name to its position, filled from the header.
+	index map[string]int
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return &csvReader{csv: reader}
+}
+
+func (c *csvReader) readHeader() error {
+	header, err := c.csv.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%w: catalog is empty", ErrMalformed)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	c.index = map[string]int{}
+	for i, name := range header {
+		// Spreadsheets often save CSV with a byte order mark.
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !isColumn(name) {
+			return fmt.Errorf("%w: unknown column %q", ErrMalformed, name)
+		}
+		c.index[name] = i
+	}
+	for _, name := range []string{"name", "genre"} {
+		if _, ok := c.index[name]; !ok {
+			return fmt.Errorf("%w: column %q is missing", ErrMalformed, name)
+		}
+	}
+	return nil
+}
+
+func (c *csvReader) Read() (Record, error) {
+	if c.index == nil {
+		if err := c.readHeader(); err != nil {
+			return Record{}, err
+		}
+	}
+	fields, err := c.csv.Read()
+	if err == io.EOF {
+		return Record{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Record{Line: parseErr.StartLine, Err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	line, _ := c.csv.FieldPos(0)
+	if len(fields) != len(c.index) {
+		return Record{Line: line, Err: fmt.Errorf("row has %d fields, expected %d", len(fields), len(c.index))}, nil
+	}
+
+	record := Record{Line: line}
+	value := func(name string) string {
+		if i, ok := c.index[name]; ok {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+	record.Book.Name = value("name")
+	record.Genre = value("genre")
+	var errs models.ValidationErrors
+	if price := value("price"); price != "" {
+		record.Book.Price, err = strconv.ParseFloat(price, 64)
+		if err != nil {
+			errs = append(errs, models.FieldError{Field: "price", Message: "price has to be a number"})
+		}
+	}
+	if amount := value("amount"); amount != "" {
+		record.Book.Amount, err = strconv.Atoi(amount)
+		if err != nil {
+			errs = append(errs, models.FieldError{Field: "amount", Message: "amount has to be an integer"})
+		}
+	}
+	if err := normalizeISBN(&record.Book, value("isbn")); err != nil {
+		errs = append(errs, *err)
+	}
+	if len(errs) != 0 {
+		record.Err = errs
+	}
+	return record, nil
+}
+
+// normalizeISBN sets the ISBN of book, if there is one, in the form it's
+// stored in.
+func normalizeISBN(book *models.Book, isbn string) *models.FieldError {
+	if isbn == "" {
+		return nil
+	}
+	normalized, err := models.NormalizeISBN(isbn)
+	if err != nil {
+		return &models.FieldError{Field: "isbn", Message: "isbn " + err.Error()}
+	}
+	book.ISBN = normalized
+	return nil
+}
+
+func isColumn(name string) bool {
+	for _, column := range columns {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &ndjsonReader{scanner: scanner}
+}
+
+// ndjsonBook is a line of an NDJSON catalog, which names its genre.
+type ndjsonBook struct {
+	Name   string  `json:"name"`
+	Genre  string  `json:"genre"`
+	Price  float64 `json:"price"`
+	Amount int     `json:"amount"`
+	ISBN   string  `json:"isbn,omitempty"`
+}
+
+func (n *ndjsonReader) Read() (Record, error) {
+	for n.scanner.Scan() {
+		n.line++
+		data := strings.TrimSpace(n.scanner.Text())
+		if data == "" {
+			continue
+		}
+		record := Record{Line: n.line}
+		var book ndjsonBook
+		if err := json.Unmarshal([]byte(data), &book); err != nil {
+			record.Err = fmt.Errorf("invalid JSON: %v", err)
+			return record, nil
+		}
+		record.Book = models.Book{Name: strings.TrimSpace(book.Name), Price: book.Price, Amount: book.Amount}
+		record.Genre = strings.TrimSpace(book.Genre)
+		if err := normalizeISBN(&record.Book, strings.TrimSpace(book.ISBN)); err != nil {
+			record.Err = models.ValidationErrors{*err}
+		}
+		return record, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Record{}, fmt.Errorf("%w: line %d is too long", ErrMalformed, n.line+1)
+		}
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
@@ -0,0 +1,146 @@
+package catalog
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, reader Reader) ([]Record, error) {
+	t.Helper()
+	var records []Record
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name            string
+		format          string
+		input           string
+		expectedRecords []Record
+		expectedError   string
+	}{
+		{
+			name:   "CSV",
+			format: FormatCSV,
+			input: "\ufeffName,Genre,Price,Amount\n" +
+				"The Hobbit,fantasy,9.5,3\n" +
+				"\"Yes, Minister\", classics ,,\n" +
+				"Bad,fantasy,cheap,many\n" +
+				"Short,fantasy\n",
+			expectedRecords: []Record{
+				{Line: 2, Book: models.Book{Name: "The Hobbit", Price: 9.5, Amount: 3}, Genre: "fantasy"},
+				{Line: 3, Book: models.Book{Name: "Yes, Minister"}, Genre: "classics"},
+				{Line: 4, Book: models.Book{Name: "Bad"}, Genre: "fantasy", Err: models.ValidationErrors{
+					{Field: "price", Message: "price has to be a number"},
+					{Field: "amount", Message: "amount has to be an integer"},
+				}},
+				{Line: 5, Err: errors.New("row has 2 fields, expected 4")},
+			},
+		},
+		{
+			name:   "CSV with reordered columns",
+			format: FormatCSV,
+			input:  "genre,name\nfantasy,The Hobbit\n",
+			expectedRecords: []Record{
+				{Line: 2, Book: models.Book{Name: "The Hobbit"}, Genre: "fantasy"},
+			},
+		},
+		{
+			name:          "CSV with unknown column",
+			format:        FormatCSV,
+			input:         "name,genre,publisher\n",
+			expectedError: `malformed catalog: unknown column "publisher"`,
+		},
+		{
+			name:   "CSV with ISBN",
+			format: FormatCSV,
+			input: "name,genre,isbn\n" +
+				"The Hobbit,fantasy,0-261-10334-2\n" +
+				"Emma,classics,\n" +
+				"Bad,fantasy,9780261103345\n",
+			expectedRecords: []Record{
+				{Line: 2, Book: models.Book{Name: "The Hobbit", ISBN: "9780261103344"}, Genre: "fantasy"},
+				{Line: 3, Book: models.Book{Name: "Emma"}, Genre: "classics"},
+				{Line: 4, Book: models.Book{Name: "Bad"}, Genre: "fantasy", Err: models.ValidationErrors{
+					{Field: "isbn", Message: "isbn has an invalid check digit"},
+				}},
+			},
+		},
+		{
+			name:          "CSV without genre",
+			format:        FormatCSV,
+			input:         "name,price\n",
+			expectedError: `malformed catalog: column "genre" is missing`,
+		},
+		{
+			name:          "Empty CSV",
+			format:        FormatCSV,
+			input:         "",
+			expectedError: "malformed catalog: catalog is empty",
+		},
+		{
+			name:   "NDJSON",
+			format: FormatNDJSON,
+			input: `{"name":"The Hobbit","genre":"fantasy","price":9.5,"amount":3}` + "\n\n" +
+				`{"name":"Broken"` + "\n" +
+				`{"name":" Emma ","genre":"classics"}`,
+			expectedRecords: []Record{
+				{Line: 1, Book: models.Book{Name: "The Hobbit", Price: 9.5, Amount: 3}, Genre: "fantasy"},
+				{Line: 3, Err: errors.New("invalid JSON: unexpected end of JSON input")},
+				{Line: 4, Book: models.Book{Name: "Emma"}, Genre: "classics"},
+			},
+		},
+		{
+			name:   "NDJSON with ISBN",
+			format: FormatNDJSON,
+			input: `{"name":"The Hobbit","genre":"fantasy","isbn":"978-0-261-10334-4"}` + "\n" +
+				`{"name":"Bad","genre":"fantasy","isbn":"12345"}`,
+			expectedRecords: []Record{
+				{Line: 1, Book: models.Book{Name: "The Hobbit", ISBN: "9780261103344"}, Genre: "fantasy"},
+				{Line: 2, Book: models.Book{Name: "Bad"}, Genre: "fantasy", Err: models.ValidationErrors{
+					{Field: "isbn", Message: "isbn has to have 10 or 13 digits"},
+				}},
+			},
+		},
+		{
+			name:          "NDJSON line too long",
+			format:        FormatNDJSON,
+			input:         `{"name":"` + strings.Repeat("a", maxLineSize) + `"}`,
+			expectedError: "malformed catalog: line 1 is too long",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewReader(strings.NewReader(test.input), test.format)
+			assert.NoError(t, err)
+			records, err := readAll(t, reader)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.True(t, errors.Is(err, ErrMalformed))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedRecords, records)
+		})
+	}
+}
+
+func TestNewReaderUnknownFormat(t *testing.T) {
+	_, err := NewReader(strings.NewReader(""), "xlsx")
+	assert.EqualError(t, err, "format has to be one of csv, ndjson")
+}
@@ -0,0 +1,144 @@
+package catalog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatTSV = "tsv"
+
+	ContentTypeTSV = "text/tab-separated-values"
+)
+
+// exportColumns are written by every format. The id column is ignored when
+// the catalog is imported again, since books are matched by name.
+var exportColumns = []string{"id", "name", "genre", "price", "amount", "isbn"}
+
+// Writer writes books to a catalog. Output may be buffered until Flush.
+type Writer interface {
+	Write(book models.Book, genre string) error
+	Flush() error
+}
+
+// NewWriter returns a Writer for the given format. Tabular formats start
+// with a header row right away, so even an empty catalog has one.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{csv: writer}, nil
+	case FormatTSV:
+		writer := &tsvWriter{buffered: bufio.NewWriter(w)}
+		if err := writer.writeRow(exportColumns); err != nil {
+			return nil, err
+		}
+		return writer, nil
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	default:
+		return nil, fmt.Errorf("format has to be one of %s, %s, %s", FormatCSV, FormatNDJSON, FormatTSV)
+	}
+}
+
+// ContentType returns the media type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return ContentTypeCSV
+	case FormatTSV:
+		return ContentTypeTSV
+	case FormatNDJSON:
+		return ContentTypeNDJSON
+	default:
+		return "application/octet-stream"
+	}
+}
+
+type csvWriter struct {
+	csv *csv.Writer
+}
+
+func (c *csvWriter) Write(book models.Book, genre string) error {
+	return c.csv.Write(exportRow(book, genre))
+}
+
+func (c *csvWriter) Flush() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// exportRow returns the exportColumns of a book.
+func exportRow(book models.Book, genre string) []string {
+	return []string{
+		strconv.Itoa(book.ID),
+		book.Name,
+		genre,
+		strconv.FormatFloat(book.Price, 'f', -1, 64),
+		strconv.Itoa(book.Amount),
+		book.ISBN,
+	}
+}
+
+// tsvWriter writes tab separated values, which have no quoting: tabs and
+// line breaks inside a field are escaped as \t, \n and \r instead, and
+// backslashes as \\, so every row stays on one line.
+type tsvWriter struct {
+	buffered *bufio.Writer
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (t *tsvWriter) Write(book models.Book, genre string) error {
+	return t.writeRow(exportRow(book, genre))
+}
+
+func (t *tsvWriter) writeRow(fields []string) error {
+	for i, field := range fields {
+		if i > 0 {
+			if err := t.buffered.WriteByte('\t'); err != nil {
+				return err
+			}
+		}
+		if _, err := tsvEscaper.WriteString(t.buffered, field); err != nil {
+			return err
+		}
+	}
+	return t.buffered.WriteByte('\n')
+}
+
+func (t *tsvWriter) Flush() error {
+	return t.buffered.Flush()
+}
+
+type ndjsonWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+// exportedBook is a line of an NDJSON catalog, which names its genre.
+type exportedBook struct {
+	ID int `json:"id"`
+	ndjsonBook
+}
+
+func (n *ndjsonWriter) Write(book models.Book, genre string) error {
+	return n.encoder.Encode(exportedBook{
+		ID:         book.ID,
+		ndjsonBook: ndjsonBook{Name: book.Name, Genre: genre, Price: book.Price, Amount: book.Amount, ISBN: book.ISBN},
+	})
+}
+
+func (n *ndjsonWriter) Flush() error {
+	return n.buffered.Flush()
+}
@@ -0,0 +1,90 @@
+package catalog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	books := []models.Book{
+		{ID: 1, Name: "The Hobbit", Price: 9.5, Amount: 3, ISBN: "9780261103344"},
+		{ID: 2, Name: "Yes, Minister", Amount: 0},
+	}
+	tests := []struct {
+		name           string
+		format         string
+		books          []models.Book
+		expectedOutput string
+	}{
+		{
+			name:   "CSV",
+			format: FormatCSV,
+			books:  books,
+			expectedOutput: "id,name,genre,price,amount,isbn\n" +
+				"1,The Hobbit,fantasy,9.5,3,9780261103344\n" +
+				"2,\"Yes, Minister\",fantasy,0,0,\n",
+		},
+		{
+			name:   "TSV",
+			format: FormatTSV,
+			books:  books,
+			expectedOutput: "id\tname\tgenre\tprice\tamount\tisbn\n" +
+				"1\tThe Hobbit\tfantasy\t9.5\t3\t9780261103344\n" +
+				"2\tYes, Minister\tfantasy\t0\t0\t\n",
+		},
+		{
+			name:   "TSV without quoting",
+			format: FormatTSV,
+			books:  []models.Book{{ID: 3, Name: "The \"Annotated\" Hobbit\tillustrated\\2nd"}},
+			expectedOutput: "id\tname\tgenre\tprice\tamount\tisbn\n" +
+				"3\tThe \"Annotated\" Hobbit\\tillustrated\\\\2nd\tfantasy\t0\t0\t\n",
+		},
+		{
+			name:   "NDJSON",
+			format: FormatNDJSON,
+			books:  books,
+			expectedOutput: `{"id":1,"name":"The Hobbit","genre":"fantasy","price":9.5,"amount":3,"isbn":"9780261103344"}` + "\n" +
+				`{"id":2,"name":"Yes, Minister","genre":"fantasy","price":0,"amount":0}` + "\n",
+		},
+		{
+			name:           "Empty CSV",
+			format:         FormatCSV,
+			expectedOutput: "id,name,genre,price,amount,isbn\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var output bytes.Buffer
+			writer, err := NewWriter(&output, test.format)
+			assert.NoError(t, err)
+			for _, book := range test.books {
+				assert.NoError(t, writer.Write(book, "fantasy"))
+			}
+			assert.NoError(t, writer.Flush())
+			assert.Equal(t, test.expectedOutput, output.String())
+		})
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	var output bytes.Buffer
+	writer, err := NewWriter(&output, FormatCSV)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(models.Book{ID: 7, Name: "Emma", Price: 4, Amount: 1, ISBN: "9780141439587"}, "classics"))
+	assert.NoError(t, writer.Flush())
+
+	reader, err := NewReader(&output, FormatCSV)
+	assert.NoError(t, err)
+	records, err := readAll(t, reader)
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{{Line: 2, Book: models.Book{Name: "Emma", Price: 4, Amount: 1, ISBN: "9780141439587"}, Genre: "classics"}}, records)
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xlsx")
+	assert.EqualError(t, err, "format has to be one of csv, ndjson, tsv")
+}
@@ -0,0 +1,394 @@
+// Package config holds the settings of the server and loads them from
+// defaults, a YAML file, environment variables and command line flags.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+	BackendMemory   = "memory"
+)
+
+// LogLevel is the least severe kind of message logged.
+type LogLevel string
+
+const (
+	LevelDebug LogLevel = "debug"
+	LevelInfo  LogLevel = "info"
+	LevelWarn  LogLevel = "warn"
+	LevelError LogLevel = "error"
+)
+
+var logLevels = []LogLevel{LevelDebug, LevelInfo, LevelWarn, LevelError}
+
+// Enables tells whether messages of level are logged at l.
+func (l LogLevel) Enables(level LogLevel) bool {
+	return levelRank(level) >= levelRank(l)
+}
+
+func levelRank(level LogLevel) int {
+	for i, known := range logLevels {
+		if level == known {
+			return i
+		}
+	}
+	return -1
+}
+
+// sslModes are the sslmode values lib/pq supports.
+var sslModes = []string{"disable", "require", "verify-ca", "verify-full"}
+
+type Config struct {
+	Server   Server   `yaml:"server"`
+	Database Database `yaml:"database"`
+	Log      Log      `yaml:"log"`
+}
+
+type Server struct {
+	// Addr is the host:port to listen on.
+	Addr string `yaml:"addr"`
+	TLS  TLS    `yaml:"tls"`
+	// ReadHeaderTimeout, WriteTimeout and IdleTimeout are applied to every
+	// connection, zero meaning no limit. Exports stream for as long as they
+	// need, so WriteTimeout is best left at zero.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout is how long requests in flight get to finish when the
+	// server stops.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// RequireIfMatch makes writes to a book fail unless they carry If-Match.
+	RequireIfMatch bool `yaml:"require_if_match"`
+}
+
+// TLS makes the server speak HTTPS when both files are set.
+type TLS struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+type Database struct {
+	// Backend is postgres, sqlite or memory.
+	Backend string `yaml:"backend"`
+	// Host to SSLRootCert describe the Postgres connection.
+	Host        string `yaml:"host"`
+	Port        int    `yaml:"port"`
+	User        string `yaml:"user"`
+	Password    string `yaml:"password"`
+	Name        string `yaml:"name"`
+	SSLMode     string `yaml:"sslmode"`
+	SSLRootCert string `yaml:"sslrootcert"`
+	// SQLitePath is the database file of the sqlite backend.
+	SQLitePath string `yaml:"sqlite_path"`
+	// MaxOpenConns and MaxIdleConns size the Postgres connection pool, and
+	// ConnMaxLifetime is how long a connection is reused. Zero open
+	// connections or lifetime means no limit.
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// ConnectTimeout is how long to keep retrying to reach Postgres at
+	// startup, zero meaning a single attempt.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// HealthCheckInterval is how often the connection is checked, to report
+	// it through GET /health.
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"`
+	// QueryTimeout bounds every repository call, zero meaning no limit.
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// AutoMigrate applies pending migrations before serving.
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+// DSN returns the lib/pq connection string of the Postgres settings.
+func (d Database) DSN() string {
+	parts := []string{
+		"host=" + quoteDSN(d.Host),
+		"port=" + strconv.Itoa(d.Port),
+		"user=" + quoteDSN(d.User),
+		"password=" + quoteDSN(d.Password),
+		"dbname=" + quoteDSN(d.Name),
+		"sslmode=" + quoteDSN(d.SSLMode),
+	}
+	if d.SSLRootCert != "" {
+		parts = append(parts, "sslrootcert="+quoteDSN(d.SSLRootCert))
+	}
+	return strings.Join(parts, " ")
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func quoteDSN(value string) string {
+	return "'" + dsnEscaper.Replace(value) + "'"
+}
+
+type Log struct {
+	Level LogLevel `yaml:"level"`
+}
+
+// Default returns the settings used when no source sets them.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   5 * time.Second,
+		},
+		Database: Database{
+			Backend:             BackendPostgres,
+			Host:                "database",
+			Port:                5432,
+			SSLMode:             "disable",
+			SQLitePath:          "bookstore.db",
+			MaxOpenConns:        10,
+			MaxIdleConns:        5,
+			ConnMaxLifetime:     30 * time.Minute,
+			ConnectTimeout:      30 * time.Second,
+			HealthCheckInterval: 10 * time.Second,
+		},
+		Log: Log{Level: LevelInfo},
+	}
+}
+
+// Load builds the configuration from, in increasing precedence, the
+// defaults, the YAML file named by -config or CONFIG_FILE, environment
+// variables and command line flags, then validates it. Empty environment
+// variables count as unset. It returns the arguments following the flags.
+func Load(args []string, getenv func(string) string) (Config, []string, error) {
+	cfg := Default()
+	flags, envs := cfg.flagSet()
+	configFile := flags.String("config", "", "YAML configuration file ($CONFIG_FILE)")
+	if err := flags.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+	// Flags win over everything else, so what they set is put aside and
+	// applied last.
+	given := map[string]string{}
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = f.Value.String()
+	})
+
+	// The flags are bound to cfg, so it has to be reset in place.
+	cfg = Default()
+	path := *configFile
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return cfg, nil, err
+		}
+	}
+	for _, env := range envs {
+		value := getenv(env.name)
+		if value == "" {
+			continue
+		}
+		if err := flags.Set(env.flag, value); err != nil {
+			return cfg, nil, fmt.Errorf("invalid %s %q: %v", env.name, value, err)
+		}
+	}
+	// The password has no flag, since command lines are visible to every
+	// user of the machine.
+	if password := getenv("POSTGRES_PASSWORD"); password != "" {
+		cfg.Database.Password = password
+	}
+	for name, value := range given {
+		if name != "config" {
+			flags.Set(name, value)
+		}
+	}
+	return cfg, flags.Args(), cfg.Validate()
+}
+
+type envVar struct {
+	name string
+	flag string
+}
+
+// flagSet returns flags bound to the fields of c, and the environment
+// variable of each flag, in flag order.
+func (c *Config) flagSet() (*flag.FlagSet, []envVar) {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	var envs []envVar
+	bind := func(name, env string) string {
+		envs = append(envs, envVar{name: env, flag: name})
+		return " ($" + env + ")"
+	}
+	str := func(p *string, name, env, usage string) {
+		flags.StringVar(p, name, *p, usage+bind(name, env))
+	}
+	num := func(p *int, name, env, usage string) {
+		flags.IntVar(p, name, *p, usage+bind(name, env))
+	}
+	duration := func(p *time.Duration, name, env, usage string) {
+		flags.DurationVar(p, name, *p, usage+bind(name, env))
+	}
+	boolean := func(p *bool, name, env, usage string) {
+		flags.BoolVar(p, name, *p, usage+bind(name, env))
+	}
+
+	str(&c.Server.Addr, "addr", "LISTEN_ADDR", "host:port to listen on")
+	str(&c.Server.TLS.CertFile, "tls-cert", "TLS_CERT_FILE", "certificate file, to serve HTTPS")
+	str(&c.Server.TLS.KeyFile, "tls-key", "TLS_KEY_FILE", "private key file, to serve HTTPS")
+	duration(&c.Server.ReadHeaderTimeout, "read-header-timeout", "SERVER_READ_HEADER_TIMEOUT", "time to read request headers, 0 for no limit")
+	duration(&c.Server.WriteTimeout, "write-timeout", "SERVER_WRITE_TIMEOUT", "time to write a response, 0 for no limit")
+	duration(&c.Server.IdleTimeout, "idle-timeout", "SERVER_IDLE_TIMEOUT", "time to keep idle connections open, 0 for no limit")
+	duration(&c.Server.ShutdownTimeout, "shutdown-timeout", "SERVER_SHUTDOWN_TIMEOUT", "time requests get to finish when stopping")
+	boolean(&c.Server.RequireIfMatch, "require-if-match", "REQUIRE_IF_MATCH", "reject book writes without If-Match")
+	str(&c.Database.Backend, "db-backend", "DB_BACKEND", "database backend, postgres, sqlite or memory")
+	str(&c.Database.Host, "db-host", "DB_HOST", "Postgres host")
+	num(&c.Database.Port, "db-port", "DB_PORT", "Postgres port")
+	str(&c.Database.User, "db-user", "POSTGRES_USER", "Postgres user")
+	str(&c.Database.Name, "db-name", "POSTGRES_DB", "Postgres database")
+	str(&c.Database.SSLMode, "db-sslmode", "DB_SSLMODE", "Postgres sslmode, disable, require, verify-ca or verify-full")
+	str(&c.Database.SSLRootCert, "db-sslrootcert", "DB_SSLROOTCERT", "CA certificate file to verify Postgres with")
+	str(&c.Database.SQLitePath, "sqlite-path", "SQLITE_PATH", "database file of the sqlite backend")
+	num(&c.Database.MaxOpenConns, "db-max-open-conns", "DB_MAX_OPEN_CONNS", "most open Postgres connections, 0 for no limit")
+	num(&c.Database.MaxIdleConns, "db-max-idle-conns", "DB_MAX_IDLE_CONNS", "most idle Postgres connections")
+	duration(&c.Database.ConnMaxLifetime, "db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "time a Postgres connection is reused, 0 for no limit")
+	duration(&c.Database.ConnectTimeout, "db-connect-timeout", "DB_CONNECT_TIMEOUT", "time to keep retrying to reach Postgres at startup, 0 for a single attempt")
+	duration(&c.Database.HealthCheckInterval, "db-health-check-interval", "DB_HEALTH_CHECK_INTERVAL", "time between checks of the database connection")
+	duration(&c.Database.QueryTimeout, "db-query-timeout", "DB_QUERY_TIMEOUT", "time limit of every database call, 0 for none")
+	boolean(&c.Database.AutoMigrate, "auto-migrate", "AUTO_MIGRATE", "apply pending migrations before serving")
+	str((*string)(&c.Log.Level), "log-level", "LOG_LEVEL", "least severe messages logged, debug, info, warn or error")
+	return flags, envs
+}
+
+// loadFile reads the YAML file at path over c. Unknown keys are errors, to
+// catch misspelled settings.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// ValidationErrors lists every problem Validate found.
+type ValidationErrors []string
+
+func (e ValidationErrors) Error() string {
+	return "invalid configuration: " + strings.Join(e, "; ")
+}
+
+// Validate checks that the settings make sense together, returning
+// ValidationErrors if they don't.
+func (c Config) Validate() error {
+	var problems ValidationErrors
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if _, port, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		problem("addr has to be host:port")
+	} else if !validPort(port, true) {
+		problem("addr has to have a port from 0 to 65535")
+	}
+	tls := c.Server.TLS
+	if tls.Enabled() {
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			problem("tls needs both a certificate and a key file")
+		}
+		for _, file := range []string{tls.CertFile, tls.KeyFile} {
+			if _, err := os.Stat(file); file != "" && err != nil {
+				problem("tls file %s can't be read", file)
+			}
+		}
+	}
+	if c.Server.ReadHeaderTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		problem("server timeouts can't be negative")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problem("shutdown timeout has to be positive")
+	}
+
+	d := c.Database
+	switch d.Backend {
+	case BackendPostgres:
+		if d.Host == "" {
+			problem("database host is required")
+		}
+		if !validPort(strconv.Itoa(d.Port), false) {
+			problem("database port has to be from 1 to 65535")
+		}
+		if d.User == "" {
+			problem("database user is required")
+		}
+		if d.Name == "" {
+			problem("database name is required")
+		}
+		if !contains(sslModes, d.SSLMode) {
+			problem("database sslmode has to be one of %s", strings.Join(sslModes, ", "))
+		}
+		if _, err := os.Stat(d.SSLRootCert); d.SSLRootCert != "" && err != nil {
+			problem("database sslrootcert %s can't be read", d.SSLRootCert)
+		}
+		if d.ConnMaxLifetime < 0 || d.ConnectTimeout < 0 {
+			problem("database connection timeouts can't be negative")
+		}
+		if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 {
+			problem("database pool sizes can't be negative")
+		} else if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+			problem("database can't keep more idle connections than it opens")
+		}
+	case BackendSQLite:
+		if d.SQLitePath == "" {
+			problem("sqlite path is required")
+		}
+	case BackendMemory:
+	default:
+		problem("database backend has to be one of postgres, sqlite, memory")
+	}
+	if d.QueryTimeout < 0 {
+		problem("database query timeout can't be negative")
+	}
+	if d.HealthCheckInterval <= 0 {
+		problem("database health check interval has to be positive")
+	}
+
+	if levelRank(c.Log.Level) < 0 {
+		problem("log level has to be one of debug, info, warn, error")
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func validPort(port string, allowZero bool) bool {
+	n, err := strconv.Atoi(port)
+	if err != nil || n > 65535 {
+		return false
+	}
+	return n > 0 || (allowZero && n == 0)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
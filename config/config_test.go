@@ -0,0 +1,185 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	file := writeFile(t, `
+server:
+  addr: ":9000"
+  shutdown_timeout: 30s
+database:
+  host: db.internal
+  port: 6432
+  user: file-user
+  name: books
+  max_open_conns: 20
+log:
+  level: warn
+`)
+	postgresEnv := map[string]string{"POSTGRES_USER": "app", "POSTGRES_DB": "books"}
+
+	tests := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		expect func(cfg *Config)
+		rest   []string
+	}{
+		{
+			name:   "defaults",
+			env:    postgresEnv,
+			expect: func(cfg *Config) { cfg.Database.User, cfg.Database.Name = "app", "books" },
+		},
+		{
+			name: "file",
+			env:  map[string]string{"CONFIG_FILE": file},
+			expect: func(cfg *Config) {
+				cfg.Server.Addr = ":9000"
+				cfg.Server.ShutdownTimeout = 30 * time.Second
+				cfg.Database.Host, cfg.Database.Port = "db.internal", 6432
+				cfg.Database.User, cfg.Database.Name = "file-user", "books"
+				cfg.Database.MaxOpenConns = 20
+				cfg.Log.Level = LevelWarn
+			},
+		},
+		{
+			name: "env over file",
+			args: []string{"-config", file},
+			env:  map[string]string{"DB_PORT": "5433", "POSTGRES_PASSWORD": "secret", "AUTO_MIGRATE": "true"},
+			expect: func(cfg *Config) {
+				cfg.Server.Addr = ":9000"
+				cfg.Server.ShutdownTimeout = 30 * time.Second
+				cfg.Database.Host, cfg.Database.Port = "db.internal", 5433
+				cfg.Database.User, cfg.Database.Password, cfg.Database.Name = "file-user", "secret", "books"
+				cfg.Database.MaxOpenConns = 20
+				cfg.Database.AutoMigrate = true
+				cfg.Log.Level = LevelWarn
+			},
+		},
+		{
+			name: "flags over env",
+			args: []string{"-db-port", "5434", "-log-level", "debug", "-db-backend", "sqlite", "migrate", "up"},
+			env:  map[string]string{"CONFIG_FILE": file, "DB_PORT": "5433", "LOG_LEVEL": "error"},
+			expect: func(cfg *Config) {
+				cfg.Server.Addr = ":9000"
+				cfg.Server.ShutdownTimeout = 30 * time.Second
+				cfg.Database.Backend = BackendSQLite
+				cfg.Database.Host, cfg.Database.Port = "db.internal", 5434
+				cfg.Database.User, cfg.Database.Name = "file-user", "books"
+				cfg.Database.MaxOpenConns = 20
+				cfg.Log.Level = LevelDebug
+			},
+			rest: []string{"migrate", "up"},
+		},
+		{
+			name:   "empty env is unset",
+			env:    map[string]string{"DB_BACKEND": "memory", "LISTEN_ADDR": ""},
+			expect: func(cfg *Config) { cfg.Database.Backend = BackendMemory },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, rest, err := Load(test.args, func(name string) string { return test.env[name] })
+			assert.NoError(t, err)
+			expected := Default()
+			test.expect(&expected)
+			assert.Equal(t, expected, cfg)
+			if test.rest == nil {
+				assert.Empty(t, rest)
+			} else {
+				assert.Equal(t, test.rest, rest)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		error string
+	}{
+		{
+			name:  "malformed env",
+			env:   map[string]string{"DB_QUERY_TIMEOUT": "soon"},
+			error: `invalid DB_QUERY_TIMEOUT "soon": parse error`,
+		},
+		{
+			name:  "unknown file key",
+			args:  []string{"-config", writeFile(t, "database:\n  hots: db\n")},
+			error: "field hots not found in type config.Database",
+		},
+		{
+			name:  "missing file",
+			args:  []string{"-config", "missing.yaml"},
+			error: "open missing.yaml: no such file or directory",
+		},
+		{
+			name: "invalid",
+			args: []string{"-addr", "8080", "-db-sslmode", "prefer", "-db-max-open-conns", "2", "-tls-key", "key.pem"},
+			env:  map[string]string{"POSTGRES_USER": "app", "LOG_LEVEL": "verbose"},
+			error: "invalid configuration: addr has to be host:port; tls needs both a certificate and a key file; " +
+				"tls file key.pem can't be read; database name is required; " +
+				"database sslmode has to be one of disable, require, verify-ca, verify-full; " +
+				"database can't keep more idle connections than it opens; " +
+				"log level has to be one of debug, info, warn, error",
+		},
+		{
+			name: "invalid connection timeouts",
+			args: []string{"-db-connect-timeout", "-1s", "-db-health-check-interval", "0"},
+			env:  map[string]string{"POSTGRES_USER": "app", "POSTGRES_DB": "books"},
+			error: "invalid configuration: database connection timeouts can't be negative; " +
+				"database health check interval has to be positive",
+		},
+		{
+			name:  "unknown backend",
+			env:   map[string]string{"DB_BACKEND": "mysql"},
+			error: "invalid configuration: database backend has to be one of postgres, sqlite, memory",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := Load(test.args, func(name string) string { return test.env[name] })
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.error)
+			}
+		})
+	}
+}
+
+func TestDSN(t *testing.T) {
+	database := Default().Database
+	database.User, database.Password, database.Name = "app", `it's a \secret`, "books"
+	assert.Equal(t,
+		`host='database' port=5432 user='app' password='it\'s a \\secret' dbname='books' sslmode='disable'`,
+		database.DSN())
+
+	database.SSLMode, database.SSLRootCert = "verify-full", "/etc/ssl/ca.pem"
+	assert.Equal(t,
+		`host='database' port=5432 user='app' password='it\'s a \\secret' dbname='books' sslmode='verify-full' sslrootcert='/etc/ssl/ca.pem'`,
+		database.DSN())
+}
+
+func TestLogLevelEnables(t *testing.T) {
+	assert.True(t, LevelInfo.Enables(LevelInfo))
+	assert.True(t, LevelInfo.Enables(LevelError))
+	assert.False(t, LevelInfo.Enables(LevelDebug))
+	assert.False(t, LevelError.Enables(LevelWarn))
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/lib/pq"
+)
+
+// ErrAuthorInUse is returned when an author is still assigned to books.
+var ErrAuthorInUse = &ConflictError{Message: "author has books"}
+
+func (db Database) GetAllAuthors(ctx context.Context) (*models.AuthorList, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	list := &models.AuthorList{}
+	rows, err := db.Conn.QueryContext(ctx, `SELECT id, name FROM authors ORDER BY id`)
+	if err != nil {
+		return list, translateError(ctx, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var author models.Author
+		if err := rows.Scan(&author.ID, &author.Name); err != nil {
+			return list, err
+		}
+		list.Authors = append(list.Authors, author)
+	}
+	return list, rows.Err()
+}
+
+func (db Database) GetAuthorByID(ctx context.Context, authorId int) (models.Author, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	author := models.Author{}
+	query := `SELECT id, name FROM authors WHERE id = $1;`
+	err := db.Conn.QueryRowContext(ctx, query, authorId).Scan(&author.ID, &author.Name)
+	return author, translateError(ctx, err)
+}
+
+func (db Database) CreateAuthor(ctx context.Context, author *models.Author) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int
+	query := `INSERT INTO authors (name) VALUES ($1) RETURNING id`
+	if err := db.Conn.QueryRowContext(ctx, query, author.Name).Scan(&id); err != nil {
+		return 0, translateError(ctx, err)
+	}
+	author.ID = id
+	return id, nil
+}
+
+func (db Database) UpdateAuthorByID(ctx context.Context, authorId int, authorData models.Author) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int
+	query := `UPDATE authors SET name=$1 WHERE id=$2 RETURNING id;`
+	if err := db.Conn.QueryRowContext(ctx, query, authorData.Name, authorId).Scan(&id); err != nil {
+		return 0, translateError(ctx, err)
+	}
+	return id, nil
+}
+
+func (db Database) DeleteAuthorByID(ctx context.Context, authorId int) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM authors WHERE id = $1;`
+	result, err := db.Conn.ExecContext(ctx, query, authorId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return ErrAuthorInUse
+		}
+		return translateError(ctx, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNoMatch
+	}
+	return nil
+}
+
+// loadAuthors fills in the author summaries of the given books with a
+// single query.
+func (db Database) loadAuthors(ctx context.Context, books []models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(books))
+	for _, book := range books {
+		ids = append(ids, int64(book.ID))
+	}
+	query := `SELECT ba.book_id, a.id, a.name FROM book_authors ba
+		JOIN authors a ON a.id = ba.author_id
+		WHERE ba.book_id = ANY($1) ORDER BY a.name`
+	rows, err := db.Conn.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	defer rows.Close()
+	authors := map[int][]models.AuthorSummary{}
+	for rows.Next() {
+		var bookID int
+		var author models.AuthorSummary
+		if err := rows.Scan(&bookID, &author.ID, &author.Name); err != nil {
+			return err
+		}
+		authors[bookID] = append(authors[bookID], author)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range books {
+		books[i].Authors = authors[books[i].ID]
+	}
+	return nil
+}
+
+// addBookAuthors assigns authors to a book inside the given transaction.
+func addBookAuthors(ctx context.Context, tx *sql.Tx, bookID int, authors []models.AuthorSummary) error {
+	if len(authors) == 0 {
+		return nil
+	}
+	seen := map[int]bool{}
+	ids := make([]int64, 0, len(authors))
+	for _, author := range authors {
+		if !seen[author.ID] {
+			seen[author.ID] = true
+			ids = append(ids, int64(author.ID))
+		}
+	}
+	query := `INSERT INTO book_authors (book_id, author_id) SELECT $1, unnest($2::int[])`
+	if _, err := tx.ExecContext(ctx, query, bookID, pq.Array(ids)); err != nil {
+		return translateError(ctx, err)
+	}
+	return nil
+}
+
+// replaceBookAuthors drops the current authors of a book and assigns the
+// given ones inside the transaction.
+func replaceBookAuthors(ctx context.Context, tx *sql.Tx, bookID int, authors []models.AuthorSummary) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = $1`, bookID); err != nil {
+		return translateError(ctx, err)
+	}
+	return addBookAuthors(ctx, tx, bookID, authors)
+}
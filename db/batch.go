@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+)
+
+// BatchBooks runs operations in order inside one transaction and returns a
+// result for each of them, with Err set on the ones that failed. Atomic
+// batches stop and roll back at the first failure, leaving the remaining
+// results empty. Otherwise every operation runs under its own savepoint and
+// only failed ones are undone. The error is only set when the transaction
+// itself fails.
+//
+// QueryTimeout isn't applied, since a large batch can legitimately take
+// longer than any single query; the request context still bounds it.
+func (db Database) BatchBooks(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error) {
+	return runBatch(ctx, db.Conn, operations, atomic, db.runOperation, nil)
+}
+
+// runBatch is BatchBooks for any backend speaking SQL savepoints, with run
+// carrying out a single operation inside the transaction. undone, if set,
+// is called in the transaction once a failed atomic batch has been rolled
+// back, with the results so far, and the transaction is committed after it.
+func runBatch(ctx context.Context, conn *sql.DB, operations []models.BatchOperation, atomic bool,
+	run func(ctx context.Context, tx *sql.Tx, operation models.BatchOperation) (int, error),
+	undone func(ctx context.Context, tx *sql.Tx, results []models.BatchResult) error) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(operations))
+	for i, operation := range operations {
+		results[i].Op = operation.Op
+		results[i].ID = operation.ID
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return results, err
+	}
+	defer tx.Rollback()
+	if atomic && undone != nil {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch`); err != nil {
+			return results, err
+		}
+	}
+
+	for i, operation := range operations {
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_operation`); err != nil {
+				return results, err
+			}
+		}
+		id, err := run(ctx, tx, operation)
+		if err != nil {
+			results[i].Err = err
+			if atomic {
+				return results, undo(ctx, tx, results, undone)
+			}
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_operation`); err != nil {
+				return results, err
+			}
+			continue
+		}
+		results[i].ID = id
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_operation`); err != nil {
+				return results, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (db Database) runOperation(ctx context.Context, tx *sql.Tx, operation models.BatchOperation) (int, error) {
+	switch operation.Op {
+	case models.BatchCreate:
+		return insertBook(ctx, tx, operation.Book)
+	case models.BatchUpdate:
+		book := *operation.Book
+		book.Version = operation.Version
+		return db.updateBook(ctx, tx, operation.ID, book)
+	case models.BatchDelete:
+		return operation.ID, db.deleteBook(ctx, tx, operation.ID, operation.Version)
+	default:
+		return 0, fmt.Errorf("unknown batch operation %q", operation.Op)
+	}
+}
+
+// undo rolls a failed atomic batch back to the savepoint runBatch set for
+// undone and commits what undone did. Without undone the deferred rollback
+// takes care of it.
+func undo(ctx context.Context, tx *sql.Tx, results []models.BatchResult,
+	undone func(ctx context.Context, tx *sql.Tx, results []models.BatchResult) error) error {
+	if undone == nil {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch`); err != nil {
+		return err
+	}
+	if err := undone(ctx, tx, results); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"regexp"
+	"testing"
+)
+
+func TestBatchBooks(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+
+	book := &models.Book{Name: "book1", Genre: 1, Price: 3.5, Amount: 2}
+	operations := []models.BatchOperation{
+		{Op: models.BatchCreate, Book: book},
+		{Op: models.BatchUpdate, ID: 2, Version: 4, Book: book},
+		{Op: models.BatchDelete, ID: 3},
+	}
+	duplicate := &pq.Error{Code: "23505", Constraint: "books_name_key"}
+
+	tests := []struct {
+		name            string
+		atomic          bool
+		mockBehavior    func()
+		expectedResults []models.BatchResult
+	}{
+		{
+			name:   "Atomic Ok",
+			atomic: true,
+			mockBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO books`).WithArgs("book1", 1, 3.5, 2, "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(7, created, created))
+				mock.ExpectQuery(regexp.QuoteMeta(`UPDATE books`)).WithArgs("book1", 1, 3.5, 2, "", 2, 4).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_authors WHERE book_id = $1`)).WithArgs(2).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM books`)).WithArgs(3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedResults: []models.BatchResult{
+				{Op: models.BatchCreate, ID: 7},
+				{Op: models.BatchUpdate, ID: 2},
+				{Op: models.BatchDelete, ID: 3},
+			},
+		},
+		{
+			name:   "Atomic stops at first failure",
+			atomic: true,
+			mockBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO books`).WillReturnError(duplicate)
+				mock.ExpectRollback()
+			},
+			expectedResults: []models.BatchResult{
+				{Op: models.BatchCreate, Err: &ConflictError{Message: "book name isn't unique", Err: duplicate}},
+				{Op: models.BatchUpdate, ID: 2},
+				{Op: models.BatchDelete, ID: 3},
+			},
+		},
+		{
+			name: "Best effort undoes failed operations",
+			mockBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`SAVEPOINT batch_operation`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`INSERT INTO books`).WillReturnError(duplicate)
+				mock.ExpectExec(`ROLLBACK TO SAVEPOINT batch_operation`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`SAVEPOINT batch_operation`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(`UPDATE books`)).WithArgs("book1", 1, 3.5, 2, "", 2, 4).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS`)).WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectExec(`ROLLBACK TO SAVEPOINT batch_operation`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`SAVEPOINT batch_operation`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM books`)).WithArgs(3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`RELEASE SAVEPOINT batch_operation`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			expectedResults: []models.BatchResult{
+				{Op: models.BatchCreate, Err: &ConflictError{Message: "book name isn't unique", Err: duplicate}},
+				{Op: models.BatchUpdate, ID: 2, Err: ErrVersionMismatch},
+				{Op: models.BatchDelete, ID: 3},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockBehavior()
+			results, err := repo.BatchBooks(context.Background(), operations, test.atomic)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedResults, results)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
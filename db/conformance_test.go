@@ -0,0 +1,530 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/migrations"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// The conformance tests check that every DatabaseBooksManager behaves the
+// same. Each backend provides a function returning an empty repository that
+// only holds the genres of the first migration.
+type openRepository func(t *testing.T) DatabaseBooksManager
+
+func TestMemoryConformance(t *testing.T) {
+	testConformance(t, func(t *testing.T) DatabaseBooksManager {
+		return NewMemoryDatabase()
+	})
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	testConformance(t, func(t *testing.T) DatabaseBooksManager {
+		repo := openSQLite(t, filepath.Join(t.TempDir(), "books.db"))
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+// TestPostgresConformance runs against the database named by
+// POSTGRES_TEST_DSN, migrating and emptying it before every test.
+func TestPostgresConformance(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN isn't set")
+	}
+	testConformance(t, func(t *testing.T) DatabaseBooksManager {
+		conn, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatalf("Could not open database: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		runner, err := migrations.NewRunner(conn, migrations.Postgres)
+		if err != nil {
+			t.Fatalf("Could not load migrations: %v", err)
+		}
+		if _, err := runner.Up(context.Background()); err != nil {
+			t.Fatalf("Could not migrate database: %v", err)
+		}
+		_, err = conn.Exec(`TRUNCATE book_authors, books, authors, genres RESTART IDENTITY CASCADE;
+			INSERT INTO genres (name) VALUES ('adventure'), ('classics'), ('fantasy');`)
+		if err != nil {
+			t.Fatalf("Could not empty database: %v", err)
+		}
+		return Database{Conn: conn}
+	})
+}
+
+func testConformance(t *testing.T, open openRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, open openRepository)
+	}{
+		{"GetAllBooks", testGetAllBooks},
+		{"GetAllBooksPages", testGetAllBooksPages},
+		{"TamperedCursor", testTamperedCursor},
+		{"Search", testSearch},
+		{"CreateBook", testCreateBook},
+		{"UpdateBookByID", testUpdateBookByID},
+		{"DeleteBookByID", testDeleteBookByID},
+		{"UpsertBook", testUpsertBook},
+		{"BatchBooks", testBatchBooks},
+		{"ExportBooks", testExportBooks},
+		{"Genres", testGenres},
+		{"ConcurrentCreates", testConcurrentCreates},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, open)
+		})
+	}
+}
+
+// seedRepository opens a repository holding three books, the second one
+// out of stock and written by the only author.
+func seedRepository(t *testing.T, open openRepository) DatabaseBooksManager {
+	repo := open(t)
+	ctx := context.Background()
+	_, err := repo.CreateAuthor(ctx, &models.Author{Name: "J. R. R. Tolkien"})
+	assert.NoError(t, err)
+	for _, book := range []models.Book{
+		{Name: "Treasure Island", Genre: 1, Price: 10, Amount: 3},
+		{Name: "The Hobbit", Genre: 3, Price: 25.5, Amount: 0, ISBN: "9780261103344", Authors: []models.AuthorSummary{{ID: 1}}},
+		{Name: "Great Expectations", Genre: 2, Price: 15, Amount: 1},
+	} {
+		_, err := repo.CreateBook(ctx, &book)
+		assert.NoError(t, err)
+	}
+	return repo
+}
+
+// assertError checks that err has the type and message of expected,
+// whatever driver error it wraps.
+func assertError(t *testing.T, expected error, err error) {
+	if expected == nil {
+		assert.NoError(t, err)
+		return
+	}
+	if assert.Error(t, err) {
+		assert.IsType(t, expected, err)
+		assert.Equal(t, expected.Error(), err.Error())
+	}
+}
+
+func bookIDs(books []models.Book) []int {
+	ids := []int{}
+	for _, book := range books {
+		ids = append(ids, book.ID)
+	}
+	return ids
+}
+
+func testGetAllBooks(t *testing.T, open openRepository) {
+	tests := []struct {
+		name          string
+		filter        models.BookFilter
+		page          models.Page
+		expectedIDs   []int
+		expectedError error
+	}{
+		{
+			name:        "In stock by default",
+			expectedIDs: []int{3, 1},
+		},
+		{
+			name:        "All",
+			filter:      models.BookFilter{Availability: models.AvailabilityAll},
+			expectedIDs: []int{3, 2, 1},
+		},
+		{
+			name:        "Out of stock",
+			filter:      models.BookFilter{Availability: models.AvailabilityOutOfStock},
+			expectedIDs: []int{2},
+		},
+		{
+			name: "Genres",
+			filter: models.BookFilter{Availability: models.AvailabilityAll, Conditions: []models.Condition{
+				{Field: "genre", Op: models.OpEq, Values: []interface{}{1, 3}},
+			}},
+			expectedIDs: []int{2, 1},
+		},
+		{
+			name: "Price range",
+			filter: models.BookFilter{Availability: models.AvailabilityAll, Conditions: []models.Condition{
+				{Field: "price", Op: models.OpGte, Values: []interface{}{15.0}},
+				{Field: "price", Op: models.OpLt, Values: []interface{}{20.0}},
+			}},
+			expectedIDs: []int{3},
+		},
+		{
+			name: "Name contains",
+			filter: models.BookFilter{Availability: models.AvailabilityAll, Conditions: []models.Condition{
+				{Field: "name", Op: models.OpContains, Values: []interface{}{"HOBB"}},
+			}},
+			expectedIDs: []int{2},
+		},
+		{
+			name: "Author",
+			filter: models.BookFilter{Availability: models.AvailabilityAll, Conditions: []models.Condition{
+				{Field: "author", Op: models.OpEq, Values: []interface{}{1}},
+			}},
+			expectedIDs: []int{2},
+		},
+		{
+			name:        "Sorted by name",
+			page:        models.Page{Sort: models.Sort{{Field: "name"}}},
+			expectedIDs: []int{3, 1},
+		},
+		{
+			name:        "Sorted by price descending",
+			filter:      models.BookFilter{Availability: models.AvailabilityAll},
+			page:        models.Page{Sort: models.Sort{{Field: "price", Desc: true}}},
+			expectedIDs: []int{2, 3, 1},
+		},
+		{
+			name:        "Search",
+			filter:      models.BookFilter{Availability: models.AvailabilityAll, Search: "the hob"},
+			expectedIDs: []int{2},
+		},
+		{
+			name:          "Unknown filter",
+			filter:        models.BookFilter{Conditions: []models.Condition{{Field: "publisher", Op: models.OpEq, Values: []interface{}{"x"}}}},
+			expectedIDs:   []int{},
+			expectedError: errors.New("can't filter books by publisher"),
+		},
+		{
+			name:          "Cursor of another sort",
+			page:          models.Page{Sort: models.Sort{{Field: "name"}}, Cursor: models.Cursor{ID: 1, Sort: "price"}},
+			expectedIDs:   []int{},
+			expectedError: errInvalidCursor,
+		},
+	}
+
+	repo := seedRepository(t, open)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list, err := repo.GetAllBooks(context.Background(), test.filter, test.page)
+			assertError(t, test.expectedError, err)
+			assert.Equal(t, test.expectedIDs, bookIDs(list.Books))
+		})
+	}
+}
+
+func testGetAllBooksPages(t *testing.T, open openRepository) {
+	tests := []struct {
+		name        string
+		sort        models.Sort
+		expectedIDs []int
+	}{
+		{
+			name:        "Price",
+			sort:        models.Sort{{Field: "price"}},
+			expectedIDs: []int{1, 3, 2},
+		},
+		{
+			name:        "Name descending",
+			sort:        models.Sort{{Field: "name", Desc: true}},
+			expectedIDs: []int{1, 2, 3},
+		},
+		{
+			name:        "Oldest first",
+			sort:        models.Sort{{Field: "created_at"}, {Field: "id"}},
+			expectedIDs: []int{1, 2, 3},
+		},
+	}
+
+	repo := seedRepository(t, open)
+	filter := models.BookFilter{Availability: models.AvailabilityAll}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ids []int
+			page := models.Page{Limit: 2, Sort: test.sort}
+			for {
+				list, err := repo.GetAllBooks(context.Background(), filter, page)
+				if !assert.NoError(t, err) {
+					return
+				}
+				ids = append(ids, bookIDs(list.Books)...)
+				if !list.HasMore {
+					break
+				}
+				// Cursors reach the repository after a round trip through the URL.
+				page.Cursor, err = models.DecodeCursor(list.NextCursor)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedIDs, ids)
+		})
+	}
+}
+
+func testTamperedCursor(t *testing.T, open openRepository) {
+	tests := []struct {
+		name   string
+		sort   models.Sort
+		cursor models.Cursor
+	}{
+		{name: "String price", sort: models.Sort{{Field: "price"}}, cursor: models.Cursor{ID: 1, Keys: []interface{}{"cheap"}, Sort: "price"}},
+		{name: "Number name", sort: models.Sort{{Field: "name"}}, cursor: models.Cursor{ID: 1, Keys: []interface{}{7.0}, Sort: "name"}},
+		{name: "Object created at", sort: models.Sort{{Field: "created_at"}},
+			cursor: models.Cursor{ID: 1, Keys: []interface{}{map[string]interface{}{"a": 1.0}}, Sort: "created_at"}},
+	}
+
+	repo := seedRepository(t, open)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := repo.GetAllBooks(context.Background(), models.BookFilter{}, models.Page{Sort: test.sort, Cursor: test.cursor})
+			assert.Equal(t, errInvalidCursor, err)
+		})
+	}
+}
+
+func testSearch(t *testing.T, open openRepository) {
+	repo := seedRepository(t, open)
+
+	list, err := repo.GetAllBooks(context.Background(), models.BookFilter{Availability: models.AvailabilityAll, Search: "hob"}, models.Page{})
+	assert.NoError(t, err)
+	if assert.Len(t, list.Books, 1) {
+		assert.Equal(t, "The <mark>Hobbit</mark>", list.Books[0].Highlight)
+		assert.Greater(t, list.Books[0].Rank, 0.0)
+		assert.Equal(t, []models.AuthorSummary{{ID: 1, Name: "J. R. R. Tolkien"}}, list.Books[0].Authors)
+	}
+
+	// Punctuation alone leaves nothing to search for.
+	filter := models.BookFilter{Availability: models.AvailabilityAll, Search: "!!!"}
+	_, err = repo.GetAllBooks(context.Background(), filter, models.Page{})
+	assert.Equal(t, errEmptySearch, err)
+	err = repo.ExportBooks(context.Background(), filter, func(book models.Book, genre string) error { return nil })
+	assert.Equal(t, errEmptySearch, err)
+}
+
+func testCreateBook(t *testing.T, open openRepository) {
+	tests := []struct {
+		name          string
+		inputBook     models.Book
+		expectedId    int
+		expectedError error
+	}{
+		{
+			name:       "Ok",
+			inputBook:  models.Book{Name: "Emma", Genre: 2, Price: 8, Amount: 2},
+			expectedId: 4,
+		},
+		{
+			name:          "Duplicate name",
+			inputBook:     models.Book{Name: "The Hobbit", Genre: 3, Price: 8, Amount: 2},
+			expectedError: &ConflictError{Message: "book name isn't unique"},
+		},
+		{
+			name:          "Duplicate ISBN",
+			inputBook:     models.Book{Name: "Hobbit", Genre: 3, Price: 8, Amount: 2, ISBN: "9780261103344"},
+			expectedError: &ConflictError{Message: "book ISBN isn't unique"},
+		},
+		{
+			name:          "Unknown genre",
+			inputBook:     models.Book{Name: "Dune", Genre: 42, Price: 8, Amount: 2},
+			expectedError: &ValidationError{Message: "genre doesn't exist"},
+		},
+		{
+			name:          "Unknown author",
+			inputBook:     models.Book{Name: "Dune", Genre: 3, Price: 8, Amount: 2, Authors: []models.AuthorSummary{{ID: 7}}},
+			expectedError: &ValidationError{Message: "author doesn't exist"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := seedRepository(t, open)
+			id, err := repo.CreateBook(context.Background(), &test.inputBook)
+			assertError(t, test.expectedError, err)
+			assert.Equal(t, test.expectedId, id)
+			assert.Equal(t, test.expectedId, test.inputBook.ID)
+		})
+	}
+}
+
+func testUpdateBookByID(t *testing.T, open openRepository) {
+	tests := []struct {
+		name          string
+		bookId        int
+		inputBook     models.Book
+		expectedISBN  string
+		expectedError error
+	}{
+		{
+			name:      "Ok",
+			bookId:    1,
+			inputBook: models.Book{Name: "Treasure Island", Genre: 1, Price: 12, Amount: 3, Version: 1},
+		},
+		{
+			name:         "Without ISBN keeps it",
+			bookId:       2,
+			inputBook:    models.Book{Name: "The Hobbit", Genre: 3, Price: 12, Amount: 3},
+			expectedISBN: "9780261103344",
+		},
+		{
+			name:         "New ISBN",
+			bookId:       2,
+			inputBook:    models.Book{Name: "The Hobbit", Genre: 3, Price: 12, Amount: 3, ISBN: "9780261102217"},
+			expectedISBN: "9780261102217",
+		},
+		{
+			name:      "Cleared ISBN",
+			bookId:    2,
+			inputBook: models.Book{Name: "The Hobbit", Genre: 3, Price: 12, Amount: 3, ClearISBN: true},
+		},
+		{
+			name:          "Stale version",
+			bookId:        1,
+			inputBook:     models.Book{Name: "Treasure Island", Genre: 1, Price: 12, Amount: 3, Version: 2},
+			expectedError: ErrVersionMismatch,
+		},
+		{
+			name:          "Duplicate name",
+			bookId:        1,
+			inputBook:     models.Book{Name: "The Hobbit", Genre: 1, Price: 12, Amount: 3},
+			expectedError: &ConflictError{Message: "book name isn't unique"},
+		},
+		{
+			name:          "Not found",
+			bookId:        9,
+			inputBook:     models.Book{Name: "Emma", Genre: 1, Price: 12, Amount: 3},
+			expectedError: ErrNoMatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := seedRepository(t, open)
+			_, err := repo.UpdateBookByID(context.Background(), test.bookId, test.inputBook)
+			assertError(t, test.expectedError, err)
+			if err == nil {
+				book, err := repo.GetBookByID(context.Background(), test.bookId)
+				assert.NoError(t, err)
+				assert.Equal(t, 12.0, book.Price)
+				assert.Equal(t, 2, book.Version)
+				assert.Equal(t, test.expectedISBN, book.ISBN)
+			}
+		})
+	}
+}
+
+func testDeleteBookByID(t *testing.T, open openRepository) {
+	repo := seedRepository(t, open)
+	ctx := context.Background()
+
+	assertError(t, ErrVersionMismatch, repo.DeleteBookByID(ctx, 2, 5))
+	assertError(t, ErrAuthorInUse, repo.DeleteAuthorByID(ctx, 1))
+	assert.NoError(t, repo.DeleteBookByID(ctx, 2, 1))
+	assertError(t, ErrNoMatch, repo.DeleteBookByID(ctx, 2, 0))
+	assert.NoError(t, repo.DeleteAuthorByID(ctx, 1))
+}
+
+func testUpsertBook(t *testing.T, open openRepository) {
+	repo := seedRepository(t, open)
+	ctx := context.Background()
+
+	created, err := repo.UpsertBook(ctx, &models.Book{Name: "Treasure Island", Genre: 1, Price: 11, Amount: 5})
+	assert.NoError(t, err)
+	assert.False(t, created)
+	book, err := repo.GetBookByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, book.Amount)
+	assert.Equal(t, 2, book.Version)
+
+	created, err = repo.UpsertBook(ctx, &models.Book{Name: "Emma", Genre: 2, Price: 8, Amount: 2})
+	assert.NoError(t, err)
+	assert.True(t, created)
+}
+
+func testBatchBooks(t *testing.T, open openRepository) {
+	operations := []models.BatchOperation{
+		{Op: models.BatchCreate, Book: &models.Book{Name: "Emma", Genre: 2, Price: 8, Amount: 2}},
+		{Op: models.BatchDelete, ID: 9},
+	}
+
+	t.Run("Atomic", func(t *testing.T) {
+		repo := seedRepository(t, open)
+		results, err := repo.BatchBooks(context.Background(), operations, true)
+		assert.NoError(t, err)
+		assertError(t, ErrNoMatch, results[1].Err)
+		_, err = repo.GetBookByID(context.Background(), 4)
+		assertError(t, ErrNoMatch, err)
+
+		// Like a Postgres sequence, the id taken by the rolled back create
+		// isn't handed out again.
+		id, err := repo.CreateBook(context.Background(), &models.Book{Name: "Dune", Genre: 1, Price: 9, Amount: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, 5, id)
+	})
+
+	t.Run("Best effort", func(t *testing.T) {
+		repo := seedRepository(t, open)
+		results, err := repo.BatchBooks(context.Background(), operations, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, results[0].ID)
+		assertError(t, ErrNoMatch, results[1].Err)
+		_, err = repo.GetBookByID(context.Background(), 4)
+		assert.NoError(t, err)
+	})
+}
+
+func testExportBooks(t *testing.T, open openRepository) {
+	repo := seedRepository(t, open)
+
+	var exported []string
+	filter := models.BookFilter{Availability: models.AvailabilityAll, Search: "the"}
+	err := repo.ExportBooks(context.Background(), filter, func(book models.Book, genre string) error {
+		exported = append(exported, book.Name+"/"+genre)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"The Hobbit/fantasy"}, exported)
+}
+
+func testGenres(t *testing.T, open openRepository) {
+	repo := seedRepository(t, open)
+	ctx := context.Background()
+
+	_, err := repo.CreateGenre(ctx, &models.Genre{Name: "fantasy"})
+	assertError(t, &ConflictError{Message: "genre name isn't unique"}, err)
+	id, err := repo.CreateGenre(ctx, &models.Genre{Name: "poetry"})
+	assert.NoError(t, err)
+	genre, err := repo.GetGenreByID(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "poetry", genre.Name)
+	_, err = repo.UpdateGenreByID(ctx, id, models.Genre{Name: "classics"})
+	assertError(t, &ConflictError{Message: "genre name isn't unique"}, err)
+	assertError(t, ErrGenreInUse, repo.DeleteGenreByID(ctx, 1))
+	assert.NoError(t, repo.DeleteGenreByID(ctx, id))
+	assertError(t, ErrNoMatch, repo.DeleteGenreByID(ctx, id))
+}
+
+func testConcurrentCreates(t *testing.T, open openRepository) {
+	repo := open(t)
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.CreateBook(context.Background(), &models.Book{Name: "Emma", Genre: 2, Price: 8, Amount: 2})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		}
+	}
+	assert.Equal(t, 1, created)
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/lib/pq"
+	"math/rand"
+	"time"
+)
+
+// Options tune the connection pool of NewDatabase, and how long it keeps
+// trying to reach a database that isn't up yet.
+type Options struct {
+	// MaxOpenConns, MaxIdleConns and ConnMaxLifetime are passed to the
+	// sql.DB of the same names.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// ConnectTimeout is how long NewDatabase retries. Zero means a single
+	// attempt.
+	ConnectTimeout time.Duration
+	// Backoff spaces out the attempts, DefaultBackoff if it's zero.
+	Backoff      Backoff
+	QueryTimeout time.Duration
+	// Logf, if set, is told about every failed attempt.
+	Logf func(format string, args ...interface{})
+}
+
+// Backoff is an exponential backoff with jitter: retry n waits a random time
+// between half and all of Initial doubled n times, at most Max. The jitter
+// keeps replicas starting together from retrying in lockstep.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+var DefaultBackoff = Backoff{Initial: 100 * time.Millisecond, Max: 5 * time.Second}
+
+func (b Backoff) delay(retry int, random *rand.Rand) time.Duration {
+	delay := b.Max
+	if retry < 32 {
+		if d := b.Initial << retry; d > 0 && d < b.Max {
+			delay = d
+		}
+	}
+	half := delay / 2
+	return half + time.Duration(random.Int63n(int64(delay-half)+1))
+}
+
+// connect calls ping until it succeeds, fails for good or options'
+// ConnectTimeout runs out, and returns its last error.
+func connect(ctx context.Context, ping func(ctx context.Context) error, options Options) error {
+	backoff := options.Backoff
+	if backoff == (Backoff{}) {
+		backoff = DefaultBackoff
+	}
+	if options.ConnectTimeout <= 0 {
+		return ping(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, options.ConnectTimeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for retry := 0; ; retry++ {
+		err := ping(ctx)
+		if err == nil || !retryable(err) {
+			return err
+		}
+		delay := backoff.delay(retry, random)
+		if time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		if options.Logf != nil {
+			options.Logf("Database isn't reachable, retrying in %s: %v", delay.Round(time.Millisecond), err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable tells whether connecting may succeed later. Postgres answering
+// with an error means it's up, so only errors of a server still starting or
+// shutting down are worth waiting out; a wrong password or database isn't.
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return true
+	}
+	switch pqErr.Code.Class() {
+	case "08", "57":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Initial: 100 * time.Millisecond, Max: time.Second}
+	random := rand.New(rand.NewSource(1))
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{retry: 0, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{retry: 1, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{retry: 3, min: 400 * time.Millisecond, max: 800 * time.Millisecond},
+		{retry: 4, min: 500 * time.Millisecond, max: time.Second},
+		{retry: 80, min: 500 * time.Millisecond, max: time.Second},
+	}
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			delay := backoff.delay(test.retry, random)
+			if delay < test.min || delay > test.max {
+				t.Fatalf("retry %d waits %s, expected %s to %s", test.retry, delay, test.min, test.max)
+			}
+		}
+	}
+}
+
+func TestConnect(t *testing.T) {
+	refused := errors.New("dial tcp: connection refused")
+	startingUp := &pq.Error{Code: "57P03", Message: "the database system is starting up"}
+	badPassword := &pq.Error{Code: "28P01", Message: "password authentication failed"}
+	fast := Backoff{Initial: time.Millisecond, Max: 2 * time.Millisecond}
+
+	tests := []struct {
+		name     string
+		errors   []error
+		options  Options
+		err      error
+		attempts int
+	}{
+		{
+			name:     "succeeds after retries",
+			errors:   []error{refused, startingUp},
+			options:  Options{ConnectTimeout: time.Second, Backoff: fast},
+			attempts: 3,
+		},
+		{
+			name:     "gives up on fatal errors",
+			errors:   []error{badPassword},
+			options:  Options{ConnectTimeout: time.Second, Backoff: fast},
+			err:      badPassword,
+			attempts: 1,
+		},
+		{
+			name:     "single attempt without timeout",
+			errors:   []error{refused},
+			options:  Options{Backoff: fast},
+			err:      refused,
+			attempts: 1,
+		},
+		{
+			name:    "gives up after timeout",
+			errors:  []error{refused, refused},
+			options: Options{ConnectTimeout: 40 * time.Millisecond, Backoff: Backoff{Initial: 100 * time.Millisecond, Max: 100 * time.Millisecond}},
+			err:     refused,
+			// Waiting at least 50ms would take it past the timeout.
+			attempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			ping := func(ctx context.Context) error {
+				attempts++
+				if attempts <= len(test.errors) {
+					return test.errors[attempts-1]
+				}
+				return nil
+			}
+			var logged []string
+			test.options.Logf = func(format string, args ...interface{}) {
+				logged = append(logged, format)
+			}
+			err := connect(context.Background(), ping, test.options)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.attempts, attempts)
+			assert.Len(t, logged, test.attempts-1)
+		})
+	}
+}
@@ -1,139 +1,376 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/GlobantObrikosina/golang-rest-api/models"
 	_ "github.com/lib/pq"
-	"log"
-)
-
-const (
-	HOST = "database"
-	PORT = 5432
+	"time"
 )
 
 // ErrNoMatch is returned when we request a row that doesn't exist
-var ErrNoMatch = fmt.Errorf("no matching record")
+var ErrNoMatch = &NotFoundError{Message: "no matching record"}
+
+// ErrVersionMismatch is returned when a book was changed since the version
+// the caller based its write on.
+var ErrVersionMismatch = &PreconditionFailedError{Message: "book was modified since it was read"}
+
+// bookColumns reads a missing ISBN as an empty string.
+const bookColumns = "id, name, genre, price, amount, version, created_at, updated_at, COALESCE(isbn, '') AS isbn"
+
+// bookFields returns the destinations of bookColumns in book.
+func bookFields(book *models.Book) []interface{} {
+	return []interface{}{&book.ID, &book.Name, &book.Genre, &book.Price, &book.Amount, &book.Version,
+		&book.CreatedAt, &book.UpdatedAt, &book.ISBN}
+}
 
 type DatabaseBooksManager interface {
-	GetAllBooks(filterCondition map[string][]string) (*models.BookList, error)
-	CreateBook(book *models.Book) (int, error)
-	GetBookByID(bookId int) (models.Book, error)
-	DeleteBookByID(bookId int) error
-	UpdateBookByID(bookId int, bookData models.Book) (int, error)
+	GetAllBooks(ctx context.Context, filter models.BookFilter, page models.Page) (*models.BookList, error)
+	CreateBook(ctx context.Context, book *models.Book) (int, error)
+	GetBookByID(ctx context.Context, bookId int) (models.Book, error)
+	GetBookByISBN(ctx context.Context, isbn string) (models.Book, error)
+	DeleteBookByID(ctx context.Context, bookId int, version int) error
+	UpdateBookByID(ctx context.Context, bookId int, bookData models.Book) (int, error)
+	BatchBooks(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error)
+	UpsertBook(ctx context.Context, book *models.Book) (bool, error)
+	ExportBooks(ctx context.Context, filter models.BookFilter, fn func(book models.Book, genre string) error) error
+	GetAllGenres(ctx context.Context) (*models.GenreList, error)
+	GetGenreByID(ctx context.Context, genreId int) (models.Genre, error)
+	CreateGenre(ctx context.Context, genre *models.Genre) (int, error)
+	UpdateGenreByID(ctx context.Context, genreId int, genreData models.Genre) (int, error)
+	DeleteGenreByID(ctx context.Context, genreId int) error
+	GetAllAuthors(ctx context.Context) (*models.AuthorList, error)
+	GetAuthorByID(ctx context.Context, authorId int) (models.Author, error)
+	CreateAuthor(ctx context.Context, author *models.Author) (int, error)
+	UpdateAuthorByID(ctx context.Context, authorId int, authorData models.Author) (int, error)
+	DeleteAuthorByID(ctx context.Context, authorId int) error
+	Ping(ctx context.Context) error
 	Close() error
 }
 
 type Database struct {
 	Conn *sql.DB
+	// QueryTimeout bounds every repository call. Zero means calls are only
+	// limited by the caller's context.
+	QueryTimeout time.Duration
 }
 
-func NewDatabase(username, password, database string) Database {
-	db := Database{}
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		HOST, PORT, username, password, database)
+// NewDatabase connects to the Postgres database described by the lib/pq
+// connection string dsn, retrying as options allow while it can't be
+// reached.
+func NewDatabase(ctx context.Context, dsn string, options Options) (Database, error) {
 	conn, err := sql.Open("postgres", dsn)
 	if err != nil {
-		log.Fatalf("Could not set up database: %v", err)
-		return db
+		return Database{}, err
 	}
-	db.Conn = conn
-	err = db.Conn.Ping()
-	if err != nil {
-		log.Fatalf("Could not set up database: %v", err)
-		return db
+	conn.SetMaxOpenConns(options.MaxOpenConns)
+	conn.SetMaxIdleConns(options.MaxIdleConns)
+	conn.SetConnMaxLifetime(options.ConnMaxLifetime)
+	if err := connect(ctx, conn.PingContext, options); err != nil {
+		conn.Close()
+		return Database{}, fmt.Errorf("could not connect to database: %w", err)
 	}
-	log.Println("Database connection established")
-	return db
+	return Database{Conn: conn, QueryTimeout: options.QueryTimeout}, nil
 }
 
-func (db Database) GetAllBooks(booksFilter map[string][]string) (*models.BookList, error) {
+func (db Database) GetAllBooks(ctx context.Context, filter models.BookFilter, page models.Page) (*models.BookList, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	list := &models.BookList{}
-	var query string
-	var err error
-	rows := &sql.Rows{}
-
-	_, okGenre := booksFilter["genre"]
-	_, okName := booksFilter["name"]
-	if len(booksFilter) == 0 {
-		query = "SELECT * FROM books WHERE amount > 0 ORDER BY ID DESC"
-		rows, err = db.Conn.Query(query)
-	} else if okGenre && okName {
-		query = "SELECT * FROM books WHERE amount > 0 AND genre = $1 AND name = $2 ORDER BY ID DESC"
-		rows, err = db.Conn.Query(query, booksFilter["genre"][0], booksFilter["name"][0])
-	} else if okGenre {
-		query = "SELECT * FROM books WHERE amount > 0 AND genre = $1 ORDER BY ID DESC"
-		rows, err = db.Conn.Query(query, booksFilter["genre"][0])
-	} else if okName {
-		query = "SELECT * FROM books WHERE amount > 0 AND name = $1 ORDER BY ID DESC"
-		rows, err = db.Conn.Query(query, booksFilter["name"][0])
+	builder := &queryBuilder{}
+	if err := builder.filterBooks(filter); err != nil {
+		return list, err
+	}
+	if page.Limit <= 0 {
+		page.Limit = models.DefaultPageLimit
 	}
 
+	searching := filter.Search != ""
+	keys := bookOrder(page.Sort, searching)
+	if !page.Cursor.IsZero() && page.Cursor.Sort != page.Sort.String() {
+		return list, errInvalidCursor
+	}
+	order, err := orderBy(keys)
 	if err != nil {
 		return list, err
 	}
+	var query string
+	if searching {
+		query = searchBooks(builder, filter.Search)
+		// The outer query numbers its arguments after the inner one's.
+		builder = &queryBuilder{args: builder.args}
+	} else {
+		query = "SELECT " + bookColumns + " FROM books"
+	}
+	if !page.Cursor.IsZero() {
+		if err := builder.after(keys, page.Cursor); err != nil {
+			return list, err
+		}
+	}
+	// One extra row tells us whether another page exists.
+	query += builder.whereClause() + order + " LIMIT " + builder.arg(page.Limit+1)
+
+	rows, err := db.Conn.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return list, translateError(ctx, err)
+	}
+	defer rows.Close()
 	for rows.Next() {
 		var book models.Book
-		err := rows.Scan(&book.ID, &book.Name, &book.Genre, &book.Price, &book.Amount)
-		if err != nil {
+		dest := bookFields(&book)
+		if searching {
+			dest = append(dest, &book.Rank, &book.Highlight)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return list, err
 		}
+		book.Available = book.Amount > 0
 		list.Books = append(list.Books, book)
 	}
+	if err := rows.Err(); err != nil {
+		return list, err
+	}
+	if len(list.Books) > page.Limit {
+		list.Books = list.Books[:page.Limit]
+		list.HasMore = true
+		list.NextCursor = nextCursor(list.Books[page.Limit-1], keys, page.Sort).Encode()
+	}
+	if err := db.loadAuthors(ctx, list.Books); err != nil {
+		return list, err
+	}
 	return list, nil
 }
 
-func (db Database) CreateBook(book *models.Book) (int, error) {
-	var id int
-	query := `INSERT INTO books (name, genre, price, amount) VALUES ($1, $2, $3, $4) RETURNING id`
-	err := db.Conn.QueryRow(query, book.Name, book.Genre, book.Price, book.Amount).Scan(&id)
+// searchBooks returns a query for the books matching both the builder's
+// conditions and text, each row carrying its relevance and a highlighted
+// name.
+func searchBooks(builder *queryBuilder, text string) string {
+	tsquery, raw := builder.search(text)
+	return "SELECT " + bookColumns + ", rank, highlight FROM (" +
+		"SELECT " + bookColumns +
+		", ts_rank(search, " + tsquery + ") + word_similarity(" + raw + ", name) AS rank" +
+		", ts_headline('simple', name, " + tsquery + ", 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS highlight" +
+		" FROM books" + builder.whereClause() + ") AS matches"
+}
+
+func (db Database) CreateBook(ctx context.Context, book *models.Book) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertBook(ctx, tx, book)
 	if err != nil {
 		book.ID = 0
 		return 0, err
 	}
+	if err := tx.Commit(); err != nil {
+		book.ID = 0
+		return 0, err
+	}
 	book.ID = id
 	return id, nil
 }
 
-func (db Database) GetBookByID(bookId int) (models.Book, error) {
+func insertBook(ctx context.Context, tx *sql.Tx, book *models.Book) (int, error) {
+	var id int
+	query := `INSERT INTO books (name, genre, price, amount, isbn) VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, created_at, updated_at`
+	err := tx.QueryRowContext(ctx, query, book.Name, book.Genre, book.Price, book.Amount, book.ISBN).
+		Scan(&id, &book.CreatedAt, &book.UpdatedAt)
+	if err != nil {
+		return 0, translateError(ctx, err)
+	}
+	if err := addBookAuthors(ctx, tx, id, book.Authors); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpsertBook creates a book or, if one with the same name exists, overwrites
+// its genre, price and amount, and its ISBN if book has one. Authors of an
+// existing book are kept.
+func (db Database) UpsertBook(ctx context.Context, book *models.Book) (bool, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO books (name, genre, price, amount, isbn) VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (name) DO UPDATE SET genre = EXCLUDED.genre, price = EXCLUDED.price,
+		amount = EXCLUDED.amount, isbn = COALESCE(EXCLUDED.isbn, books.isbn),
+		version = books.version + 1, updated_at = now()
+		RETURNING id, xmax = 0`
+	var created bool
+	err := db.Conn.QueryRowContext(ctx, query, book.Name, book.Genre, book.Price, book.Amount, book.ISBN).
+		Scan(&book.ID, &created)
+	if err != nil {
+		book.ID = 0
+		return false, translateError(ctx, err)
+	}
+	return created, nil
+}
+
+func (db Database) GetBookByID(ctx context.Context, bookId int) (models.Book, error) {
+	return db.getBook(ctx, "id", bookId)
+}
+
+// GetBookByISBN finds a book by its normalized ISBN-13.
+func (db Database) GetBookByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	return db.getBook(ctx, "isbn", isbn)
+}
+
+// getBook finds a book by a unique column.
+func (db Database) getBook(ctx context.Context, column string, value interface{}) (models.Book, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	book := models.Book{}
-	query := `SELECT * FROM books WHERE id = $1;`
-	row := db.Conn.QueryRow(query, bookId)
-	switch err := row.Scan(&book.ID, &book.Name, &book.Genre, &book.Price, &book.Amount); err {
-	case sql.ErrNoRows:
-		return book, ErrNoMatch
-	default:
+	query := `SELECT ` + bookColumns + ` FROM books WHERE ` + column + ` = $1;`
+	row := db.Conn.QueryRowContext(ctx, query, value)
+	err := row.Scan(bookFields(&book)...)
+	if err != nil {
+		return book, translateError(ctx, err)
+	}
+	book.Available = book.Amount > 0
+	books := []models.Book{book}
+	if err := db.loadAuthors(ctx, books); err != nil {
 		return book, err
 	}
+	return books[0], nil
 }
 
-func (db Database) DeleteBookByID(bookId int) error {
+// DeleteBookByID removes a book. A non-zero version makes the delete
+// conditional on the book still being at that version.
+func (db Database) DeleteBookByID(ctx context.Context, bookId int, version int) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.deleteBook(ctx, db.Conn, bookId, version)
+}
+
+func (db Database) deleteBook(ctx context.Context, conn execQueryRower, bookId int, version int) error {
 	query := `DELETE FROM books WHERE id = $1;`
-	_, err := db.Conn.Exec(query, bookId)
-	switch err {
-	case sql.ErrNoRows:
-		return ErrNoMatch
-	default:
+	args := []interface{}{bookId}
+	if version > 0 {
+		query = `DELETE FROM books WHERE id = $1 AND version = $2;`
+		args = append(args, version)
+	}
+	result, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
 		return err
 	}
+	if affected == 0 {
+		return missingBookError(ctx, conn, bookId, version)
+	}
+	return nil
 }
 
-func (db Database) UpdateBookByID(bookId int, bookData models.Book) (int, error) {
-	query := `UPDATE books SET name=$1, genre=$2, price=$3, amount=$4 WHERE id=$5 RETURNING id;`
-	var newBookID int
-	err := db.Conn.QueryRow(
-		query, bookData.Name, bookData.Genre, bookData.Price, bookData.Amount, bookId).Scan(&newBookID)
+// UpdateBookByID overwrites a book. A non-zero bookData.Version makes the
+// update conditional on the book still being at that version.
+func (db Database) UpdateBookByID(ctx context.Context, bookId int, bookData models.Book) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 
+	tx, err := db.Conn.BeginTx(ctx, nil)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, ErrNoMatch
-		}
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	newBookID, err := db.updateBook(ctx, tx, bookId, bookData)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return newBookID, nil
+}
+
+func (db Database) updateBook(ctx context.Context, tx *sql.Tx, bookId int, bookData models.Book) (int, error) {
+	query := `UPDATE books SET name=$1, genre=$2, price=$3, amount=$4, isbn=` + updatedISBN(bookData) + `,
+		version=version+1, updated_at=now() WHERE id=$6`
+	args := []interface{}{bookData.Name, bookData.Genre, bookData.Price, bookData.Amount, bookData.ISBN, bookId}
+	if bookData.Version > 0 {
+		query += ` AND version=$7`
+		args = append(args, bookData.Version)
+	}
+	var newBookID int
+	err := tx.QueryRowContext(ctx, query+` RETURNING id;`, args...).Scan(&newBookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, missingBookError(ctx, tx, bookId, bookData.Version)
+	}
+	if err != nil {
+		return 0, translateError(ctx, err)
+	}
+	if err := replaceBookAuthors(ctx, tx, newBookID, bookData.Authors); err != nil {
 		return 0, err
 	}
 	return newBookID, nil
 }
 
+// updatedISBN is the expression an update sets the isbn column to, given the
+// ISBN as $5.
+func updatedISBN(bookData models.Book) string {
+	if bookData.ClearISBN {
+		return `NULLIF($5, '')`
+	}
+	return `COALESCE(NULLIF($5, ''), isbn)`
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// execQueryRower is satisfied by both *sql.DB and *sql.Tx.
+type execQueryRower interface {
+	queryRower
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// missingBookError explains why a conditional write touched no rows: the
+// book is either gone or at another version.
+func missingBookError(ctx context.Context, conn queryRower, bookId int, version int) error {
+	if version == 0 {
+		return ErrNoMatch
+	}
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM books WHERE id = $1);`
+	if err := conn.QueryRowContext(ctx, query, bookId).Scan(&exists); err != nil {
+		return translateError(ctx, err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return ErrNoMatch
+}
+
+// withTimeout applies QueryTimeout on top of any deadline ctx already has.
+func (db Database) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, db.QueryTimeout)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (db Database) Ping(ctx context.Context) error {
+	return db.Conn.PingContext(ctx)
+}
+
 func (db Database) Close() error {
 	return db.Conn.Close()
 }
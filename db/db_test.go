@@ -1,21 +1,32 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"regexp"
 	"testing"
+	"time"
 )
 
+var created = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
 func MockDB() (DatabaseBooksManager, sqlmock.Sqlmock, error) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		return nil, nil, err
 	}
-	return Database{db}, mock, nil
+	return Database{Conn: db}, mock, nil
+}
+
+func expectAuthors(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM book_authors`)).WillReturnRows(rows)
 }
 
 func TestGetAllBooks(t *testing.T) {
@@ -25,85 +36,351 @@ func TestGetAllBooks(t *testing.T) {
 		return
 	}
 
-	type mockBehavior func(filterCondition map[string][]string)
+	bookRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "genre", "price", "amount", "version", "created_at", "updated_at", "isbn"})
+	}
+	searchRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "genre", "price", "amount", "version", "created_at", "updated_at", "isbn", "rank", "highlight"})
+	}
 	tests := []struct {
-		name            string
-		mockBehavior    mockBehavior
-		filterCondition map[string][]string
-		expectedBooks   []models.Book
-		expectError     bool
+		name               string
+		mockBehavior       func()
+		filter             models.BookFilter
+		page               models.Page
+		expectedBooks      []models.Book
+		expectedNextCursor string
+		expectedHasMore    bool
+		expectError        bool
 	}{
 		{
 			name: "Ok",
-			mockBehavior: func(filterCondition map[string][]string) {
-				mock.ExpectQuery("SELECT").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "genre", "price", "amount"}).
-						AddRow(1, "book1", 1, 3.7, 1).
-						AddRow(2, "book2", 2, 4.7, 2).
-						AddRow(3, "book3", 3, 5.7, 3))
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM books WHERE amount > 0 ORDER BY id DESC LIMIT $1`)).
+					WithArgs(models.DefaultPageLimit + 1).
+					WillReturnRows(bookRows().
+						AddRow(1, "book1", 1, 3.7, 1, 1, created, created, "").
+						AddRow(2, "book2", 2, 4.7, 2, 1, created, created, "").
+						AddRow(3, "book3", 3, 5.7, 3, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}).
+					AddRow(1, 7, "Jules Verne").
+					AddRow(3, 7, "Jules Verne").
+					AddRow(3, 8, "Mark Twain"))
+			},
+			expectedBooks: []models.Book{
+				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created,
+					Authors: []models.AuthorSummary{{ID: 7, Name: "Jules Verne"}}},
+				{ID: 2, Name: "book2", Genre: 2, Price: 4.7, Amount: 2, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+				{ID: 3, Name: "book3", Genre: 3, Price: 5.7, Amount: 3, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created,
+					Authors: []models.AuthorSummary{{ID: 7, Name: "Jules Verne"}, {ID: 8, Name: "Mark Twain"}}},
+			},
+		},
+		{
+			name: "Filter genre",
+			filter: models.BookFilter{Conditions: []models.Condition{
+				{Field: "genre", Op: models.OpEq, Values: []interface{}{1}},
+			}},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE amount > 0 AND genre = $1 ORDER BY id DESC LIMIT $2`)).
+					WithArgs(1, models.DefaultPageLimit+1).
+					WillReturnRows(bookRows().AddRow(1, "book1", 1, 3.7, 1, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
 			},
 			expectedBooks: []models.Book{
-				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1},
-				{ID: 2, Name: "book2", Genre: 2, Price: 4.7, Amount: 2},
-				{ID: 3, Name: "book3", Genre: 3, Price: 5.7, Amount: 3},
+				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
 			},
 		},
 		{
-			name:            "Filter genre",
-			filterCondition: map[string][]string{"genre": {"1"}},
-			mockBehavior: func(filterCondition map[string][]string) {
-				genreId := filterCondition["genre"][0]
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(genreId).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount"}).
-						AddRow(1, "book1", 1, 3.7, 1))
+			name:   "Out of stock",
+			filter: models.BookFilter{Availability: models.AvailabilityOutOfStock},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM books WHERE amount <= 0 ORDER BY id DESC LIMIT $1`)).
+					WithArgs(models.DefaultPageLimit + 1).
+					WillReturnRows(bookRows().AddRow(1, "book1", 1, 3.7, 0, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
 			},
 			expectedBooks: []models.Book{
-				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1},
+				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 0, Version: 1, CreatedAt: created, UpdatedAt: created},
 			},
 		},
 		{
-			name:            "Filter name",
-			filterCondition: map[string][]string{"name": {"book1"}},
-			mockBehavior: func(filterCondition map[string][]string) {
-				name := filterCondition["name"][0]
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(name).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount"}).
-						AddRow(1, "book1", 1, 3.7, 1))
+			name: "All availabilities",
+			filter: models.BookFilter{
+				Conditions:   []models.Condition{{Field: "genre", Op: models.OpEq, Values: []interface{}{1}}},
+				Availability: models.AvailabilityAll,
+			},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM books WHERE genre = $1 ORDER BY id DESC LIMIT $2`)).
+					WithArgs(1, models.DefaultPageLimit+1).
+					WillReturnRows(bookRows().
+						AddRow(2, "book2", 1, 3.7, 0, 1, created, created, "").
+						AddRow(1, "book1", 1, 3.7, 5, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
 			},
 			expectedBooks: []models.Book{
-				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1},
+				{ID: 2, Name: "book2", Genre: 1, Price: 3.7, Amount: 0, Version: 1, CreatedAt: created, UpdatedAt: created},
+				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 5, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
 			},
 		},
 		{
-			name:            "Filter name and genre",
-			filterCondition: map[string][]string{"name": {"book1"}, "genre": {"1"}},
-			mockBehavior: func(filterCondition map[string][]string) {
-				name := filterCondition["name"][0]
-				genre := filterCondition["genre"][0]
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(genre, name).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount"}).
-						AddRow(1, "book1", 1, 3.7, 1))
+			name: "Filter several genres",
+			filter: models.BookFilter{Conditions: []models.Condition{
+				{Field: "genre", Op: models.OpEq, Values: []interface{}{1, 3}},
+			}},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE amount > 0 AND genre IN ($1, $2) ORDER BY id DESC LIMIT $3`)).
+					WithArgs(1, 3, models.DefaultPageLimit+1).
+					WillReturnRows(bookRows().AddRow(1, "book1", 1, 3.7, 1, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
 			},
 			expectedBooks: []models.Book{
-				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1},
+				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+			},
+		},
+		{
+			name: "Filter name and genre",
+			filter: models.BookFilter{Conditions: []models.Condition{
+				{Field: "genre", Op: models.OpEq, Values: []interface{}{1}},
+				{Field: "name", Op: models.OpEq, Values: []interface{}{"book1"}},
+			}},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE amount > 0 AND genre = $1 AND name = $2 ORDER BY id DESC LIMIT $3`)).
+					WithArgs(1, "book1", models.DefaultPageLimit+1).
+					WillReturnRows(bookRows().AddRow(1, "book1", 1, 3.7, 1, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
+			},
+			expectedBooks: []models.Book{
+				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+			},
+		},
+		{
+			name: "Filter price range and name",
+			filter: models.BookFilter{Conditions: []models.Condition{
+				{Field: "name", Op: models.OpContains, Values: []interface{}{"100%_sure"}},
+				{Field: "price", Op: models.OpGte, Values: []interface{}{10.0}},
+				{Field: "price", Op: models.OpLt, Values: []interface{}{30.0}},
+			}},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE amount > 0 AND name ILIKE $1 AND price >= $2 AND price < $3 ORDER BY id DESC LIMIT $4`)).
+					WithArgs(`%100\%\_sure%`, 10.0, 30.0, models.DefaultPageLimit+1).
+					WillReturnRows(bookRows().AddRow(1, "100%_sure", 1, 13.7, 1, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
+			},
+			expectedBooks: []models.Book{
+				{ID: 1, Name: "100%_sure", Genre: 1, Price: 13.7, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+			},
+		},
+		{
+			name: "Page after cursor",
+			filter: models.BookFilter{Conditions: []models.Condition{
+				{Field: "genre", Op: models.OpEq, Values: []interface{}{1}},
+			}},
+			page: models.Page{Limit: 2, Cursor: models.Cursor{ID: 10}},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`AND id < $2 ORDER BY id DESC LIMIT $3`)).WithArgs(1, 10, 3).
+					WillReturnRows(bookRows().
+						AddRow(9, "book9", 1, 3.7, 1, 1, created, created, "").
+						AddRow(7, "book7", 1, 4.7, 2, 1, created, created, "").
+						AddRow(4, "book4", 1, 5.7, 3, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
+			},
+			expectedBooks: []models.Book{
+				{ID: 9, Name: "book9", Genre: 1, Price: 3.7, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+				{ID: 7, Name: "book7", Genre: 1, Price: 4.7, Amount: 2, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+			},
+			expectedNextCursor: models.Cursor{ID: 7}.Encode(),
+			expectedHasMore:    true,
+		},
+		{
+			name: "Filter author",
+			filter: models.BookFilter{Conditions: []models.Condition{
+				{Field: "author", Op: models.OpEq, Values: []interface{}{7}},
+			}},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`AND id IN (SELECT book_id FROM book_authors WHERE author_id = $1)`)).
+					WithArgs(7, models.DefaultPageLimit+1).
+					WillReturnRows(bookRows().AddRow(1, "book1", 1, 3.7, 1, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}).
+					AddRow(1, 7, "Jules Verne"))
+			},
+			expectedBooks: []models.Book{
+				{ID: 1, Name: "book1", Genre: 1, Price: 3.7, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created,
+					Authors: []models.AuthorSummary{{ID: 7, Name: "Jules Verne"}}},
+			},
+		},
+		{
+			name:   "Search",
+			filter: models.BookFilter{Search: "The hobb!"},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE amount > 0 AND (search @@ to_tsquery('simple', $1) OR $2 <% name)) AS matches ORDER BY rank DESC, id DESC LIMIT $3`)).
+					WithArgs("the:* & hobb:*", "The hobb!", models.DefaultPageLimit+1).
+					WillReturnRows(searchRows().
+						AddRow(1, "The Hobbit", 3, 9.5, 2, 1, created, created, "", 0.75, "<mark>The</mark> <mark>Hobbit</mark>"))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
+			},
+			expectedBooks: []models.Book{
+				{ID: 1, Name: "The Hobbit", Genre: 3, Price: 9.5, Amount: 2, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created,
+					Rank: 0.75, Highlight: "<mark>The</mark> <mark>Hobbit</mark>"},
+			},
+		},
+		{
+			name: "Search page after cursor",
+			filter: models.BookFilter{
+				Conditions: []models.Condition{{Field: "genre", Op: models.OpEq, Values: []interface{}{3}}},
+				Search:     "hobbit",
+			},
+			page: models.Page{Limit: 1, Cursor: models.Cursor{ID: 10, Keys: []interface{}{0.5}}},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`AS matches WHERE (rank < $4 OR (rank = $4 AND id < $5)) ORDER BY rank DESC, id DESC LIMIT $6`)).
+					WithArgs(3, "hobbit:*", "hobbit", 0.5, 10, 2).
+					WillReturnRows(searchRows().
+						AddRow(8, "Hobbit", 3, 9.5, 2, 1, created, created, "", 0.5, "<mark>Hobbit</mark>").
+						AddRow(6, "Hobbits", 3, 9.5, 2, 1, created, created, "", 0.25, "<mark>Hobbits</mark>"))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
+			},
+			expectedBooks: []models.Book{
+				{ID: 8, Name: "Hobbit", Genre: 3, Price: 9.5, Amount: 2, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created,
+					Rank: 0.5, Highlight: "<mark>Hobbit</mark>"},
+			},
+			expectedNextCursor: models.Cursor{ID: 8, Keys: []interface{}{0.5}}.Encode(),
+			expectedHasMore:    true,
+		},
+		{
+			name: "Sorted page after cursor",
+			page: models.Page{
+				Limit:  1,
+				Sort:   models.Sort{{Field: "price"}, {Field: "name", Desc: true}},
+				Cursor: models.Cursor{ID: 4, Keys: []interface{}{9.5, "book4"}, Sort: "price,-name"},
+			},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE amount > 0 AND (price > $1 OR (price = $1 AND name < $2) OR `+
+					`(price = $1 AND name = $2 AND id < $3)) ORDER BY price ASC, name DESC, id DESC LIMIT $4`)).
+					WithArgs(9.5, "book4", 4, 2).
+					WillReturnRows(bookRows().
+						AddRow(3, "book3", 1, 9.5, 1, 1, created, created, "").
+						AddRow(5, "book5", 1, 12.0, 1, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
+			},
+			expectedBooks: []models.Book{
+				{ID: 3, Name: "book3", Genre: 1, Price: 9.5, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+			},
+			expectedNextCursor: models.Cursor{ID: 3, Keys: []interface{}{9.5, "book3"}, Sort: "price,-name"}.Encode(),
+			expectedHasMore:    true,
+		},
+		{
+			name:   "Newest first",
+			filter: models.BookFilter{Availability: models.AvailabilityAll},
+			page: models.Page{
+				Limit: 1,
+				Sort:  models.Sort{{Field: "created_at", Desc: true}},
+			},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM books ORDER BY created_at DESC, id DESC LIMIT $1`)).
+					WithArgs(2).
+					WillReturnRows(bookRows().
+						AddRow(6, "book6", 1, 9.5, 1, 1, created, created, "").
+						AddRow(5, "book5", 1, 12.0, 1, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
+			},
+			expectedBooks: []models.Book{
+				{ID: 6, Name: "book6", Genre: 1, Price: 9.5, Amount: 1, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+			},
+			expectedNextCursor: models.Cursor{ID: 6, Keys: []interface{}{created}, Sort: "-created_at"}.Encode(),
+			expectedHasMore:    true,
+		},
+		{
+			name: "Cursor from another order",
+			page: models.Page{
+				Sort:   models.Sort{{Field: "price"}},
+				Cursor: models.Cursor{ID: 4, Keys: []interface{}{"book4"}, Sort: "name"},
+			},
+			mockBehavior: func() {},
+			expectError:  true,
+		},
+		{
+			name: "Cursor without sort keys",
+			page: models.Page{
+				Sort:   models.Sort{{Field: "price"}},
+				Cursor: models.Cursor{ID: 4, Sort: "price"},
+			},
+			mockBehavior: func() {},
+			expectError:  true,
+		},
+		{
+			name: "Unknown filter field",
+			filter: models.BookFilter{Conditions: []models.Condition{
+				{Field: "id; DROP TABLE books", Op: models.OpEq, Values: []interface{}{1}},
+			}},
+			mockBehavior: func() {},
+			expectError:  true,
+		},
+		{
+			name: "Query error",
+			mockBehavior: func() {
+				mock.ExpectQuery("SELECT").WillReturnError(errors.New("query error"))
 			},
+			expectError: true,
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			test.mockBehavior(test.filterCondition)
-			books, err := repo.GetAllBooks(test.filterCondition)
+			test.mockBehavior()
+			books, err := repo.GetAllBooks(context.Background(), test.filter, test.page)
 			if test.expectError {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, test.expectedBooks, books.Books)
+				assert.Equal(t, test.expectedNextCursor, books.NextCursor)
+				assert.Equal(t, test.expectedHasMore, books.HasMore)
 			}
 			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
 
+func TestGetAllBooksTamperedCursor(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+
+	tests := []struct {
+		name   string
+		sort   models.Sort
+		cursor string
+	}{
+		{name: "String price", sort: models.Sort{{Field: "price"}}, cursor: `{"id":4,"keys":["cheap"],"sort":"price"}`},
+		{name: "Fractional amount", sort: models.Sort{{Field: "amount"}}, cursor: `{"id":4,"keys":[1.5],"sort":"amount"}`},
+		{name: "Number name", sort: models.Sort{{Field: "name"}}, cursor: `{"id":4,"keys":[7],"sort":"name"}`},
+		{name: "Object created at", sort: models.Sort{{Field: "created_at"}}, cursor: `{"id":4,"keys":[{"a":1}],"sort":"created_at"}`},
+		{name: "Malformed created at", sort: models.Sort{{Field: "created_at"}}, cursor: `{"id":4,"keys":["yesterday"],"sort":"created_at"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursor, err := models.DecodeCursor(base64.RawURLEncoding.EncodeToString([]byte(test.cursor)))
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = repo.GetAllBooks(context.Background(), models.BookFilter{}, models.Page{Sort: test.sort, Cursor: cursor})
+			assert.Equal(t, errInvalidCursor, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetAllBooksEmptySearch(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+
+	_, err := repo.GetAllBooks(context.Background(), models.BookFilter{Search: "!!!"}, models.Page{})
+	assert.Equal(t, errEmptySearch, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAddBook(t *testing.T) {
 	repo, mock, mockError := MockDB()
 	if mockError != nil {
@@ -130,9 +407,11 @@ func TestAddBook(t *testing.T) {
 			},
 			returnedId: 1,
 			mockBehavior: func(mock sqlmock.Sqlmock, returnedId int, book models.Book) {
+				mock.ExpectBegin()
 				mock.ExpectQuery(`INSERT INTO books`).
-					WithArgs(book.Name, book.Genre, book.Price, book.Amount).
-					WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(returnedId))
+					WithArgs(book.Name, book.Genre, book.Price, book.Amount, book.ISBN).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(returnedId, created, created))
+				mock.ExpectCommit()
 			},
 			expectError: false,
 		},
@@ -145,10 +424,12 @@ func TestAddBook(t *testing.T) {
 				Amount: 8,
 			},
 			mockBehavior: func(mock sqlmock.Sqlmock, returnedId int, book models.Book) {
+				mock.ExpectBegin()
 				mock.ExpectQuery(`INSERT INTO books`).
-					WithArgs(book.Name, book.Genre, book.Price, book.Amount).
-					WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(returnedId).
+					WithArgs(book.Name, book.Genre, book.Price, book.Amount, book.ISBN).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(returnedId, created, created).
 						RowError(0, errors.New("insert error")))
+				mock.ExpectRollback()
 			},
 			expectError: true,
 		},
@@ -162,22 +443,66 @@ func TestAddBook(t *testing.T) {
 			},
 			returnedId: 1,
 			mockBehavior: func(mock sqlmock.Sqlmock, returnedId int, book models.Book) {
+				mock.ExpectBegin()
 				mock.ExpectQuery(`INSERT INTO books`).
-					WithArgs(book.Name, book.Genre, book.Price, book.Amount).
-					WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(returnedId))
+					WithArgs(book.Name, book.Genre, book.Price, book.Amount, book.ISBN).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(returnedId, created, created))
+				mock.ExpectCommit()
 			},
 			expectError: false,
 		},
+		{
+			name: "With authors",
+			inputBook: models.Book{
+				Name:    "hello authors",
+				Price:   45.99,
+				Genre:   1,
+				Amount:  8,
+				Authors: []models.AuthorSummary{{ID: 7}, {ID: 8}, {ID: 7}},
+			},
+			returnedId: 2,
+			mockBehavior: func(mock sqlmock.Sqlmock, returnedId int, book models.Book) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO books`).
+					WithArgs(book.Name, book.Genre, book.Price, book.Amount, book.ISBN).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(returnedId, created, created))
+				mock.ExpectExec(`INSERT INTO book_authors`).WithArgs(returnedId, "{7,8}").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Unknown author",
+			inputBook: models.Book{
+				Name:    "hello authors",
+				Price:   45.99,
+				Genre:   1,
+				Amount:  8,
+				Authors: []models.AuthorSummary{{ID: 99}},
+			},
+			returnedId: 2,
+			mockBehavior: func(mock sqlmock.Sqlmock, returnedId int, book models.Book) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO books`).
+					WithArgs(book.Name, book.Genre, book.Price, book.Amount, book.ISBN).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(returnedId, created, created))
+				mock.ExpectExec(`INSERT INTO book_authors`).WithArgs(returnedId, "{99}").
+					WillReturnError(&pq.Error{Code: "23503", Constraint: "book_authors_author_id_fkey"})
+				mock.ExpectRollback()
+			},
+			expectError: true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.mockBehavior(mock, test.returnedId, test.inputBook)
-			err := repo.CreateBook(&test.inputBook)
+			_, err := repo.CreateBook(context.Background(), &test.inputBook)
 			if test.expectError {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, test.returnedId, test.inputBook.ID)
+				assert.Equal(t, created, test.inputBook.CreatedAt)
 			}
 			assert.NoError(t, mock.ExpectationsWereMet())
 		})
@@ -203,16 +528,23 @@ func TestGetBookById(t *testing.T) {
 			name: "Ok",
 			mockBehavior: func(inputId int) {
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(inputId).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount"}).
-						AddRow(1, "book1", 2, 1.11, 9))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount", "version", "created_at", "updated_at", "isbn"}).
+						AddRow(1, "book1", 2, 1.11, 9, 1, created, created, ""))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}).
+					AddRow(1, 7, "Jules Verne"))
 			},
 			inputId: 2,
 			expectedBook: models.Book{
-				ID:     1,
-				Name:   "book1",
-				Genre:  2,
-				Price:  1.11,
-				Amount: 9,
+				ID:        1,
+				Name:      "book1",
+				Genre:     2,
+				Price:     1.11,
+				Amount:    9,
+				Authors:   []models.AuthorSummary{{ID: 7, Name: "Jules Verne"}},
+				Available: true,
+				Version:   1,
+				CreatedAt: created,
+				UpdatedAt: created,
 			},
 		},
 		{
@@ -223,11 +555,20 @@ func TestGetBookById(t *testing.T) {
 			inputId:     2,
 			expectError: true,
 		},
+		{
+			name: "No rows",
+			mockBehavior: func(inputId int) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(inputId).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount", "version", "created_at", "updated_at", "isbn"}))
+			},
+			inputId:     2,
+			expectError: true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.mockBehavior(test.inputId)
-			book, err := repo.GetBookByID(test.inputId)
+			book, err := repo.GetBookByID(context.Background(), test.inputId)
 			if test.expectError {
 				assert.Error(t, err)
 			} else {
@@ -239,6 +580,53 @@ func TestGetBookById(t *testing.T) {
 	}
 }
 
+func TestGetBookByISBN(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+
+	columns := []string{"id", "name", "genre", "price", "amount", "version", "created_at", "updated_at", "isbn"}
+	tests := []struct {
+		name          string
+		mockBehavior  func()
+		expectedBook  models.Book
+		expectedError error
+	}{
+		{
+			name: "Ok",
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM books WHERE isbn = $1`)).WithArgs("9780306406157").
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "book1", 2, 1.11, 0, 1, created, created, "9780306406157"))
+				expectAuthors(mock, sqlmock.NewRows([]string{"book_id", "id", "name"}))
+			},
+			expectedBook: models.Book{ID: 1, Name: "book1", ISBN: "9780306406157", Genre: 2, Price: 1.11, Version: 1,
+				CreatedAt: created, UpdatedAt: created},
+		},
+		{
+			name: "Not found",
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM books WHERE isbn = $1`)).WithArgs("9780306406157").
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+			expectedError: ErrNoMatch,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockBehavior()
+			book, err := repo.GetBookByISBN(context.Background(), "9780306406157")
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError == nil {
+				assert.Equal(t, test.expectedBook, book)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestDeleteBook(t *testing.T) {
 	repo, mock, mockError := MockDB()
 	if mockError != nil {
@@ -248,10 +636,12 @@ func TestDeleteBook(t *testing.T) {
 
 	type mockBehavior func(inputId int)
 	tests := []struct {
-		name         string
-		inputId      int
-		mockBehavior mockBehavior
-		expectError  bool
+		name          string
+		inputId       int
+		inputVersion  int
+		mockBehavior  mockBehavior
+		expectedError error
+		expectError   bool
 	}{
 		{
 			name:    "Ok",
@@ -269,13 +659,61 @@ func TestDeleteBook(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name:    "No rows affected",
+			inputId: 4,
+			mockBehavior: func(inputId int) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE")).WithArgs(inputId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedError: ErrNoMatch,
+			expectError:   true,
+		},
+		{
+			name:         "Version matches",
+			inputId:      4,
+			inputVersion: 2,
+			mockBehavior: func(inputId int) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM books WHERE id = $1 AND version = $2")).
+					WithArgs(inputId, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:         "Version mismatch",
+			inputId:      4,
+			inputVersion: 2,
+			mockBehavior: func(inputId int) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM books WHERE id = $1 AND version = $2")).
+					WithArgs(inputId, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).WithArgs(inputId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			expectedError: ErrVersionMismatch,
+			expectError:   true,
+		},
+		{
+			name:         "Versioned delete of missing book",
+			inputId:      4,
+			inputVersion: 2,
+			mockBehavior: func(inputId int) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM books WHERE id = $1 AND version = $2")).
+					WithArgs(inputId, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).WithArgs(inputId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			expectedError: ErrNoMatch,
+			expectError:   true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.mockBehavior(test.inputId)
-			err := repo.DeleteBookByID(test.inputId)
+			err := repo.DeleteBookByID(context.Background(), test.inputId, test.inputVersion)
 			if test.expectError {
 				assert.Error(t, err)
+				if test.expectedError != nil {
+					assert.Equal(t, test.expectedError, err)
+				}
 			} else {
 				assert.NoError(t, err)
 			}
@@ -293,18 +731,23 @@ func TestUpdateBook(t *testing.T) {
 
 	type mockBehavior func(inputId int, inputBook models.Book)
 	tests := []struct {
-		name         string
-		mockBehavior mockBehavior
-		inputId      int
-		inputBook    models.Book
-		expectError  bool
+		name          string
+		mockBehavior  mockBehavior
+		inputId       int
+		inputBook     models.Book
+		expectedError error
+		expectError   bool
 	}{
 		{
 			name: "Ok",
 			mockBehavior: func(inputId int, inputBook models.Book) {
+				mock.ExpectBegin()
 				mock.ExpectQuery(`UPDATE`).
-					WithArgs(inputBook.Name, inputBook.Genre, inputBook.Price, inputBook.Amount, inputId).
+					WithArgs(inputBook.Name, inputBook.Genre, inputBook.Price, inputBook.Amount, inputBook.ISBN, inputId).
 					WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(inputId))
+				mock.ExpectExec(`DELETE FROM book_authors`).WithArgs(inputId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
 			},
 			inputId: 1,
 			inputBook: models.Book{
@@ -318,9 +761,11 @@ func TestUpdateBook(t *testing.T) {
 		{
 			name: "id not found",
 			mockBehavior: func(inputId int, inputBook models.Book) {
+				mock.ExpectBegin()
 				mock.ExpectQuery(`UPDATE`).
-					WithArgs(inputBook.Name, inputBook.Genre, inputBook.Price, inputBook.Amount, inputId).
+					WithArgs(inputBook.Name, inputBook.Genre, inputBook.Price, inputBook.Amount, inputBook.ISBN, inputId).
 					WillReturnError(errors.New("id not found"))
+				mock.ExpectRollback()
 			},
 			inputId: 1,
 			inputBook: models.Book{
@@ -332,13 +777,38 @@ func TestUpdateBook(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "Version mismatch",
+			mockBehavior: func(inputId int, inputBook models.Book) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(`version=version+1, updated_at=now() WHERE id=$6 AND version=$7`)).
+					WithArgs(inputBook.Name, inputBook.Genre, inputBook.Price, inputBook.Amount, inputBook.ISBN, inputId, inputBook.Version).
+					WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).WithArgs(inputId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			inputId: 1,
+			inputBook: models.Book{
+				Name:    "book1",
+				Genre:   2,
+				Price:   1.11,
+				Amount:  9,
+				Version: 3,
+			},
+			expectedError: ErrVersionMismatch,
+			expectError:   true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.mockBehavior(test.inputId, test.inputBook)
-			updatedBook, err := repo.UpdateBookByID(test.inputId, test.inputBook)
+			updatedBook, err := repo.UpdateBookByID(context.Background(), test.inputId, test.inputBook)
 			if test.expectError {
 				assert.Error(t, err)
+				if test.expectedError != nil {
+					assert.Equal(t, test.expectedError, err)
+				}
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, updatedBook, test.inputBook.ID)
@@ -347,3 +817,142 @@ func TestUpdateBook(t *testing.T) {
 		})
 	}
 }
+
+func TestTranslateError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timedOut, cancelTimeout := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelTimeout()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		inputErr error
+		check    func(t *testing.T, err error)
+	}{
+		{
+			name:     "No rows",
+			inputErr: sql.ErrNoRows,
+			check: func(t *testing.T, err error) {
+				assert.Equal(t, ErrNoMatch, err)
+			},
+		},
+		{
+			name:     "Unique name",
+			inputErr: &pq.Error{Code: "23505", Constraint: "books_name_key", Message: "duplicate key"},
+			check: func(t *testing.T, err error) {
+				var conflict *ConflictError
+				assert.True(t, errors.As(err, &conflict))
+				assert.Equal(t, "book name isn't unique", err.Error())
+			},
+		},
+		{
+			name:     "Query canceled",
+			ctx:      canceled,
+			inputErr: &pq.Error{Code: "57014", Message: "canceling statement due to user request"},
+			check: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, context.Canceled))
+			},
+		},
+		{
+			name:     "Query timed out",
+			ctx:      timedOut,
+			inputErr: &pq.Error{Code: "57014", Message: "canceling statement due to user request"},
+			check: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, context.DeadlineExceeded))
+			},
+		},
+		{
+			name:     "Query canceled by server",
+			inputErr: &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"},
+			check: func(t *testing.T, err error) {
+				assert.False(t, errors.Is(err, context.Canceled))
+				assert.EqualError(t, err, "pq: canceling statement due to statement timeout")
+			},
+		},
+		{
+			name:     "Unknown genre",
+			inputErr: &pq.Error{Code: "23503", Constraint: "books_genre_fkey", Message: "foreign key violation"},
+			check: func(t *testing.T, err error) {
+				var validation *ValidationError
+				assert.True(t, errors.As(err, &validation))
+				assert.Equal(t, "genre doesn't exist", err.Error())
+			},
+		},
+		{
+			name:     "Other error",
+			inputErr: errors.New("connection refused"),
+			check: func(t *testing.T, err error) {
+				assert.EqualError(t, err, "connection refused")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := test.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			test.check(t, translateError(ctx, test.inputErr))
+		})
+	}
+}
+
+func TestUpsertBook(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+
+	tests := []struct {
+		name            string
+		mockBehavior    func()
+		expectedID      int
+		expectedCreated bool
+		expectError     bool
+	}{
+		{
+			name: "Created",
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`ON CONFLICT (name) DO UPDATE`)).WithArgs("book1", 1, 3.5, 2, "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created"}).AddRow(5, true))
+			},
+			expectedID:      5,
+			expectedCreated: true,
+		},
+		{
+			name: "Updated",
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`ON CONFLICT (name) DO UPDATE`)).WithArgs("book1", 1, 3.5, 2, "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created"}).AddRow(2, false))
+			},
+			expectedID: 2,
+		},
+		{
+			name: "Unknown genre",
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`ON CONFLICT (name) DO UPDATE`)).
+					WillReturnError(&pq.Error{Code: "23503", Constraint: "books_genre_fkey"})
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockBehavior()
+			book := &models.Book{Name: "book1", Genre: 1, Price: 3.5, Amount: 2}
+			created, err := repo.UpsertBook(context.Background(), book)
+			if test.expectError {
+				var validation *ValidationError
+				assert.True(t, errors.As(err, &validation))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedID, book.ID)
+			assert.Equal(t, test.expectedCreated, created)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// NotFoundError is returned when the requested record doesn't exist.
+type NotFoundError struct {
+	Message string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+// ConflictError is returned when a write would break a uniqueness rule.
+type ConflictError struct {
+	Message string
+	Err     error
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when the database rejects the data itself,
+// e.g. a reference to a record that doesn't exist.
+type ValidationError struct {
+	Message string
+	Err     error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// PreconditionFailedError is returned when a conditional write no longer
+// matches the stored record.
+type PreconditionFailedError struct {
+	Message string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Message
+}
+
+const (
+	uniqueViolation     = pq.ErrorCode("23505")
+	foreignKeyViolation = pq.ErrorCode("23503")
+	notNullViolation    = pq.ErrorCode("23502")
+	checkViolation      = pq.ErrorCode("23514")
+	queryCanceled       = pq.ErrorCode("57014")
+)
+
+var constraintMessages = map[string]string{
+	"books_name_key":              "book name isn't unique",
+	"books_isbn_key":              "book ISBN isn't unique",
+	"books_genre_fkey":            "genre doesn't exist",
+	"genres_name_key":             "genre name isn't unique",
+	"book_authors_author_id_fkey": "author doesn't exist",
+}
+
+// translateError turns driver errors into the package's error types so
+// callers don't have to know about Postgres error codes. ctx is the one the
+// failed statement ran with.
+func translateError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoMatch
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	message, ok := constraintMessages[pqErr.Constraint]
+	if !ok {
+		message = pqErr.Message
+	}
+	switch pqErr.Code {
+	case queryCanceled:
+		// Postgres cancels statements when our context is done, but also on
+		// statement_timeout or an administrator's request, which are
+		// server errors.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%w: %s", ctxErr, pqErr.Message)
+		}
+	case uniqueViolation:
+		return &ConflictError{Message: message, Err: err}
+	case foreignKeyViolation, notNullViolation, checkViolation:
+		return &ValidationError{Message: message, Err: err}
+	}
+	return err
+}
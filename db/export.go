@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+)
+
+// ExportBooks calls fn for every book matching filter, in id order, along
+// with the name of its genre. Rows are read one at a time from the cursor,
+// so memory use doesn't grow with the catalog. Authors aren't loaded.
+//
+// QueryTimeout isn't applied, since dumping a large catalog can take longer
+// than any single query; the request context still bounds it.
+func (db Database) ExportBooks(ctx context.Context, filter models.BookFilter, fn func(book models.Book, genre string) error) error {
+	builder := &queryBuilder{}
+	if err := builder.filterBooks(filter); err != nil {
+		return err
+	}
+	if filter.Search != "" {
+		builder.search(filter.Search)
+	}
+	query := "SELECT " + bookColumns + ", (SELECT name FROM genres WHERE genres.id = books.genre)" +
+		" FROM books" + builder.whereClause() + " ORDER BY id ASC"
+
+	rows, err := db.Conn.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var book models.Book
+		var genre string
+		err := rows.Scan(append(bookFields(&book), &genre)...)
+		if err != nil {
+			return err
+		}
+		book.Available = book.Amount > 0
+		if err := fn(book, genre); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"regexp"
+	"testing"
+)
+
+func TestExportBooks(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+	columns := []string{"id", "name", "genre", "price", "amount", "version", "created_at", "updated_at", "isbn", "name"}
+	stop := errors.New("stop")
+
+	tests := []struct {
+		name          string
+		filter        models.BookFilter
+		mockBehavior  func()
+		stopAfter     int
+		expectedBooks []models.Book
+		expectedGenre []string
+		expectedError error
+	}{
+		{
+			name:   "Filtered",
+			filter: models.BookFilter{Conditions: []models.Condition{{Field: "genre", Op: models.OpEq, Values: []interface{}{1}}}},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, genre, price, amount, version, created_at, updated_at, COALESCE(isbn, '') AS isbn, " +
+					"(SELECT name FROM genres WHERE genres.id = books.genre) FROM books " +
+					"WHERE amount > 0 AND genre = $1 ORDER BY id ASC")).WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "book1", 1, 3.5, 2, 1, created, created, "", "fantasy").
+						AddRow(4, "book4", 1, 1.5, 1, 3, created, created, "9780261103344", "fantasy"))
+			},
+			expectedBooks: []models.Book{
+				{ID: 1, Name: "book1", Genre: 1, Price: 3.5, Amount: 2, Available: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+				{ID: 4, Name: "book4", Genre: 1, Price: 1.5, Amount: 1, Available: true, Version: 3, CreatedAt: created, UpdatedAt: created, ISBN: "9780261103344"},
+			},
+			expectedGenre: []string{"fantasy", "fantasy"},
+		},
+		{
+			name:   "Stopped by callback",
+			filter: models.BookFilter{Availability: models.AvailabilityAll},
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("FROM books ORDER BY id ASC")).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "book1", 1, 3.5, 0, 1, created, created, "", "fantasy").
+						AddRow(2, "book2", 1, 1.5, 1, 1, created, created, "", "fantasy"))
+			},
+			stopAfter:     1,
+			expectedBooks: []models.Book{{ID: 1, Name: "book1", Genre: 1, Price: 3.5, Version: 1, CreatedAt: created, UpdatedAt: created}},
+			expectedGenre: []string{"fantasy"},
+			expectedError: stop,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockBehavior()
+			var books []models.Book
+			var genres []string
+			err := repo.ExportBooks(context.Background(), test.filter, func(book models.Book, genre string) error {
+				books = append(books, book)
+				genres = append(genres, genre)
+				if len(books) == test.stopAfter {
+					return stop
+				}
+				return nil
+			})
+			assert.Equal(t, test.expectedError, err)
+			assert.Equal(t, test.expectedBooks, books)
+			assert.Equal(t, test.expectedGenre, genres)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
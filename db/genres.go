@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/lib/pq"
+)
+
+// ErrGenreInUse is returned when a genre still has books assigned to it.
+var ErrGenreInUse = &ConflictError{Message: "genre is used by books"}
+
+func (db Database) GetAllGenres(ctx context.Context) (*models.GenreList, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	list := &models.GenreList{}
+	rows, err := db.Conn.QueryContext(ctx, `SELECT id, name FROM genres ORDER BY id`)
+	if err != nil {
+		return list, translateError(ctx, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var genre models.Genre
+		if err := rows.Scan(&genre.ID, &genre.Name); err != nil {
+			return list, err
+		}
+		list.Genres = append(list.Genres, genre)
+	}
+	return list, rows.Err()
+}
+
+func (db Database) GetGenreByID(ctx context.Context, genreId int) (models.Genre, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	genre := models.Genre{}
+	query := `SELECT id, name FROM genres WHERE id = $1;`
+	err := db.Conn.QueryRowContext(ctx, query, genreId).Scan(&genre.ID, &genre.Name)
+	return genre, translateError(ctx, err)
+}
+
+func (db Database) CreateGenre(ctx context.Context, genre *models.Genre) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int
+	query := `INSERT INTO genres (name) VALUES ($1) RETURNING id`
+	if err := db.Conn.QueryRowContext(ctx, query, genre.Name).Scan(&id); err != nil {
+		return 0, translateError(ctx, err)
+	}
+	genre.ID = id
+	return id, nil
+}
+
+func (db Database) UpdateGenreByID(ctx context.Context, genreId int, genreData models.Genre) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int
+	query := `UPDATE genres SET name=$1 WHERE id=$2 RETURNING id;`
+	if err := db.Conn.QueryRowContext(ctx, query, genreData.Name, genreId).Scan(&id); err != nil {
+		return 0, translateError(ctx, err)
+	}
+	return id, nil
+}
+
+func (db Database) DeleteGenreByID(ctx context.Context, genreId int) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM genres WHERE id = $1;`
+	result, err := db.Conn.ExecContext(ctx, query, genreId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return ErrGenreInUse
+		}
+		return translateError(ctx, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNoMatch
+	}
+	return nil
+}
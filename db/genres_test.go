@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"regexp"
+	"testing"
+)
+
+func TestGetAllGenres(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name FROM genres`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+			AddRow(1, "adventure").
+			AddRow(2, "classics"))
+
+	genres, err := repo.GetAllGenres(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Genre{{ID: 1, Name: "adventure"}, {ID: 2, Name: "classics"}}, genres.Genres)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateGenre(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+
+	tests := []struct {
+		name          string
+		inputGenre    models.Genre
+		mockBehavior  func(genre models.Genre)
+		expectedId    int
+		expectedError error
+	}{
+		{
+			name:       "Ok",
+			inputGenre: models.Genre{Name: "poetry"},
+			mockBehavior: func(genre models.Genre) {
+				mock.ExpectQuery(`INSERT INTO genres`).WithArgs(genre.Name).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+			},
+			expectedId: 4,
+		},
+		{
+			name:       "Duplicate name",
+			inputGenre: models.Genre{Name: "fantasy"},
+			mockBehavior: func(genre models.Genre) {
+				mock.ExpectQuery(`INSERT INTO genres`).WithArgs(genre.Name).
+					WillReturnError(&pq.Error{Code: "23505", Constraint: "genres_name_key"})
+			},
+			expectedError: &ConflictError{Message: "genre name isn't unique"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockBehavior(test.inputGenre)
+			id, err := repo.CreateGenre(context.Background(), &test.inputGenre)
+			if test.expectedError != nil {
+				assert.EqualError(t, err, test.expectedError.Error())
+				assert.IsType(t, test.expectedError, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedId, id)
+				assert.Equal(t, test.expectedId, test.inputGenre.ID)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteGenre(t *testing.T) {
+	repo, mock, mockError := MockDB()
+	if mockError != nil {
+		log.Printf("Could not mock database: %v\n", mockError)
+		return
+	}
+
+	tests := []struct {
+		name          string
+		inputId       int
+		mockBehavior  func(inputId int)
+		expectedError error
+	}{
+		{
+			name:    "Ok",
+			inputId: 4,
+			mockBehavior: func(inputId int) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM genres")).WithArgs(inputId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:    "Not found",
+			inputId: 9,
+			mockBehavior: func(inputId int) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM genres")).WithArgs(inputId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedError: ErrNoMatch,
+		},
+		{
+			name:    "Used by books",
+			inputId: 1,
+			mockBehavior: func(inputId int) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM genres")).WithArgs(inputId).
+					WillReturnError(&pq.Error{Code: "23503", Constraint: "books_genre_fkey"})
+			},
+			expectedError: ErrGenreInUse,
+		},
+		{
+			name:    "Other error",
+			inputId: 1,
+			mockBehavior: func(inputId int) {
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM genres")).WithArgs(inputId).
+					WillReturnError(errors.New("connection refused"))
+			},
+			expectedError: errors.New("connection refused"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockBehavior(test.inputId)
+			err := repo.DeleteGenreByID(context.Background(), test.inputId)
+			if test.expectedError != nil {
+				assert.Equal(t, test.expectedError, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"sync"
+	"time"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthMonitor checks a database connection in the background, so that
+// losing it and getting it back are logged and reported by GET /health
+// without waiting for a request to fail. database/sql replaces broken
+// connections by itself, so the checks are all it takes to reconnect.
+type HealthMonitor struct {
+	database Pinger
+	logf     func(format string, args ...interface{})
+	now      func() time.Time
+
+	mu     sync.RWMutex
+	health models.DatabaseHealth
+}
+
+// NewHealthMonitor returns a monitor of a database that was just connected.
+// logf, if set, is told when the connection is lost and when it's back.
+func NewHealthMonitor(database Pinger, logf func(format string, args ...interface{})) *HealthMonitor {
+	m := &HealthMonitor{database: database, logf: logf, now: time.Now}
+	now := m.now()
+	m.health = models.DatabaseHealth{Status: models.HealthUp, Since: now, CheckedAt: now}
+	return m
+}
+
+// Run checks the database every interval until ctx is done, giving each
+// check the interval to answer.
+func (m *HealthMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			m.Check(checkCtx)
+			cancel()
+		}
+	}
+}
+
+// Check pings the database and records the result.
+func (m *HealthMonitor) Check(ctx context.Context) models.DatabaseHealth {
+	err := m.database.Ping(ctx)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// Stopping isn't the database's fault.
+		return m.Health()
+	}
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	health := &m.health
+	health.CheckedAt = now
+	switch {
+	case err != nil && health.Status == models.HealthUp:
+		health.Status, health.Since, health.Error = models.HealthDown, now, err.Error()
+		m.log("Database connection lost: %v", err)
+	case err != nil:
+		health.Error = err.Error()
+	case health.Status == models.HealthDown:
+		m.log("Database connection restored after %s", now.Sub(health.Since).Round(time.Second))
+		health.Status, health.Since, health.Error = models.HealthUp, now, ""
+		health.Reconnects++
+	}
+	return *health
+}
+
+// Health returns the result of the last check.
+func (m *HealthMonitor) Health() models.DatabaseHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.health
+}
+
+func (m *HealthMonitor) log(format string, args ...interface{}) {
+	if m.logf != nil {
+		m.logf(format, args...)
+	}
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type pingFunc func(ctx context.Context) error
+
+func (f pingFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestHealthMonitor(t *testing.T) {
+	refused := errors.New("connection refused")
+	var pingErr error
+	var logged []string
+	monitor := NewHealthMonitor(pingFunc(func(ctx context.Context) error { return pingErr }),
+		func(format string, args ...interface{}) { logged = append(logged, fmt.Sprintf(format, args...)) })
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	monitor.now = func() time.Time { return now }
+	monitor.health.Since, monitor.health.CheckedAt = start, start
+
+	steps := []struct {
+		err    error
+		health models.DatabaseHealth
+		logged []string
+	}{
+		{
+			health: models.DatabaseHealth{Status: models.HealthUp, Since: start, CheckedAt: start},
+		},
+		{
+			err: refused,
+			health: models.DatabaseHealth{Status: models.HealthDown, Since: start.Add(time.Minute),
+				CheckedAt: start.Add(time.Minute), Error: "connection refused"},
+			logged: []string{"Database connection lost: connection refused"},
+		},
+		{
+			err: refused,
+			health: models.DatabaseHealth{Status: models.HealthDown, Since: start.Add(time.Minute),
+				CheckedAt: start.Add(2 * time.Minute), Error: "connection refused"},
+		},
+		{
+			health: models.DatabaseHealth{Status: models.HealthUp, Since: start.Add(3 * time.Minute),
+				CheckedAt: start.Add(3 * time.Minute), Reconnects: 1},
+			logged: []string{"Database connection restored after 2m0s"},
+		},
+	}
+	for i, step := range steps {
+		now = start.Add(time.Duration(i) * time.Minute)
+		pingErr, logged = step.err, nil
+		assert.Equal(t, step.health, monitor.Check(context.Background()), "step %d", i)
+		assert.Equal(t, step.health, monitor.Health(), "step %d", i)
+		assert.Equal(t, step.logged, logged, "step %d", i)
+	}
+
+	// A check cut short by stopping says nothing about the database.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pingErr = context.Canceled
+	assert.Equal(t, models.HealthUp, monitor.Check(ctx).Status)
+}
@@ -0,0 +1,845 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// MemoryDatabase is a DatabaseBooksManager that keeps everything in memory,
+// for running the server and tests without Postgres. It follows the same
+// rules as the schema: unique book and genre names, unique ISBNs, books
+// referencing existing genres and authors, and the same filtering, ordering
+// and pagination as Database. Search matches words by prefix only, there is
+// no fuzzy matching of misspelled words.
+//
+// It is safe for concurrent use. Data is lost when the process exits.
+type MemoryDatabase struct {
+	mu sync.RWMutex
+	memoryData
+	// The last ids handed out live outside memoryData: like Postgres
+	// sequences, they aren't rolled back with a failed batch, so an id is
+	// never given to two books.
+	lastBookID   int
+	lastGenreID  int
+	lastAuthorID int
+	// now stamps created and updated books, replaced in tests.
+	now func() time.Time
+}
+
+// memoryData is the records a MemoryDatabase stores, split out so an atomic
+// batch can take a copy and put it back on failure.
+type memoryData struct {
+	books       map[int]models.Book
+	bookAuthors map[int][]int
+	genres      map[int]models.Genre
+	authors     map[int]models.Author
+}
+
+// NewMemoryDatabase returns an empty MemoryDatabase with the genres the
+// first migration creates.
+func NewMemoryDatabase() *MemoryDatabase {
+	m := &MemoryDatabase{
+		memoryData: memoryData{
+			books:       map[int]models.Book{},
+			bookAuthors: map[int][]int{},
+			genres:      map[int]models.Genre{},
+			authors:     map[int]models.Author{},
+		},
+		now: time.Now,
+	}
+	for _, name := range []string{"adventure", "classics", "fantasy"} {
+		m.lastGenreID++
+		m.genres[m.lastGenreID] = models.Genre{ID: m.lastGenreID, Name: name}
+	}
+	return m
+}
+
+func (d memoryData) clone() memoryData {
+	c := d
+	c.books = make(map[int]models.Book, len(d.books))
+	for id, book := range d.books {
+		c.books[id] = book
+	}
+	c.bookAuthors = make(map[int][]int, len(d.bookAuthors))
+	for id, authors := range d.bookAuthors {
+		c.bookAuthors[id] = append([]int(nil), authors...)
+	}
+	c.genres = make(map[int]models.Genre, len(d.genres))
+	for id, genre := range d.genres {
+		c.genres[id] = genre
+	}
+	c.authors = make(map[int]models.Author, len(d.authors))
+	for id, author := range d.authors {
+		c.authors[id] = author
+	}
+	return c
+}
+
+func (m *MemoryDatabase) GetAllBooks(ctx context.Context, filter models.BookFilter, page models.Page) (*models.BookList, error) {
+	list := &models.BookList{}
+	if err := ctx.Err(); err != nil {
+		return list, err
+	}
+	// The query builder rejects the same filters Database does.
+	if err := (&queryBuilder{}).filterBooks(filter); err != nil {
+		return list, err
+	}
+	if page.Limit <= 0 {
+		page.Limit = models.DefaultPageLimit
+	}
+	searching := filter.Search != ""
+	keys := bookOrder(page.Sort, searching)
+	if !page.Cursor.IsZero() && page.Cursor.Sort != page.Sort.String() {
+		return list, errInvalidCursor
+	}
+	for _, key := range keys {
+		if _, ok := bookSortColumns[key.Field]; !ok {
+			return list, fmt.Errorf("can't sort books by %s", key.Field)
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	books := m.filterBooks(filter)
+	sort.SliceStable(books, func(i, j int) bool {
+		return compareBooks(books[i], books[j], keys) < 0
+	})
+	if !page.Cursor.IsZero() {
+		cursor, err := cursorBook(keys, page.Cursor)
+		if err != nil {
+			return list, err
+		}
+		after := books[:0]
+		for _, book := range books {
+			if compareBooks(book, cursor, keys) > 0 {
+				after = append(after, book)
+			}
+		}
+		books = after
+	}
+	if len(books) > page.Limit {
+		books = books[:page.Limit]
+		list.HasMore = true
+		list.NextCursor = nextCursor(books[page.Limit-1], keys, page.Sort).Encode()
+	}
+	for i := range books {
+		books[i] = m.withAuthors(books[i])
+	}
+	list.Books = books
+	return list, nil
+}
+
+// filterBooks returns copies of the books matching filter, with their rank
+// and highlight when searching.
+func (m *MemoryDatabase) filterBooks(filter models.BookFilter) []models.Book {
+	var words []string
+	if filter.Search != "" {
+		words = searchWords(filter.Search)
+	}
+	var books []models.Book
+	for _, book := range m.books {
+		switch filter.Availability {
+		case models.AvailabilityAll:
+		case models.AvailabilityOutOfStock:
+			if book.Amount > 0 {
+				continue
+			}
+		default:
+			if book.Amount <= 0 {
+				continue
+			}
+		}
+		matched := true
+		for _, condition := range filter.Conditions {
+			if !m.matchCondition(book, condition) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if filter.Search != "" {
+			var ok bool
+			book.Rank, book.Highlight, ok = matchSearch(book.Name, words)
+			if !ok {
+				continue
+			}
+		}
+		books = append(books, book)
+	}
+	return books
+}
+
+// matchCondition tells whether book meets c, which the query builder has
+// already checked.
+func (m *MemoryDatabase) matchCondition(book models.Book, c models.Condition) bool {
+	if c.Op == models.OpContains {
+		return strings.Contains(strings.ToLower(book.Name), strings.ToLower(fmt.Sprint(c.Values[0])))
+	}
+	if c.Field == "author" {
+		for _, value := range c.Values {
+			for _, id := range m.bookAuthors[book.ID] {
+				if value == id {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	field := bookKey(book, c.Field)
+	for _, value := range c.Values {
+		cmp := compareKeys(field, keyValue(value))
+		switch c.Op {
+		case models.OpEq:
+			if cmp == 0 {
+				return true
+			}
+		case models.OpGt:
+			return cmp > 0
+		case models.OpGte:
+			return cmp >= 0
+		case models.OpLt:
+			return cmp < 0
+		case models.OpLte:
+			return cmp <= 0
+		}
+	}
+	return false
+}
+
+// matchSearch tells whether every word starts a word of name. The rank is
+// the share of the name's words that matched, and the highlight marks them.
+func matchSearch(name string, words []string) (float64, string, bool) {
+	if len(words) == 0 {
+		return 0, "", false
+	}
+	nameWords := searchWords(name)
+	for _, word := range words {
+		found := false
+		for _, nameWord := range nameWords {
+			if strings.HasPrefix(nameWord, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, "", false
+		}
+	}
+
+	var highlight strings.Builder
+	matched := 0
+	runes := []rune(name)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			highlight.WriteRune(runes[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+			end++
+		}
+		nameWord := string(runes[i:end])
+		isMatch := false
+		for _, word := range words {
+			if strings.HasPrefix(strings.ToLower(nameWord), word) {
+				isMatch = true
+				break
+			}
+		}
+		if isMatch {
+			matched++
+			highlight.WriteString("<mark>" + nameWord + "</mark>")
+		} else {
+			highlight.WriteString(nameWord)
+		}
+		i = end
+	}
+	return float64(matched) / float64(len(nameWords)), highlight.String(), true
+}
+
+// bookKey returns a field of book in a form compareKeys understands.
+func bookKey(book models.Book, field string) interface{} {
+	switch field {
+	case "id":
+		return float64(book.ID)
+	case "name":
+		return book.Name
+	case "isbn":
+		return book.ISBN
+	case "genre":
+		return float64(book.Genre)
+	case "price":
+		return book.Price
+	case "amount":
+		return float64(book.Amount)
+	case "created_at":
+		return book.CreatedAt
+	case "rank":
+		return book.Rank
+	}
+	return nil
+}
+
+// keyValue converts a filter or cursor value to the form bookKey returns.
+// Cursor values have been through JSON, so numbers arrive as float64 and
+// times as strings.
+func keyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
+
+// compareKeys orders two values of the same field, returning -1, 0 or 1.
+func compareKeys(a, b interface{}) int {
+	switch a := a.(type) {
+	case float64:
+		b, _ := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case string:
+		return strings.Compare(a, fmt.Sprint(b))
+	case time.Time:
+		var t time.Time
+		switch b := b.(type) {
+		case time.Time:
+			t = b
+		case string:
+			t, _ = time.Parse(time.RFC3339Nano, b)
+		}
+		switch {
+		case a.Before(t):
+			return -1
+		case a.After(t):
+			return 1
+		}
+	}
+	return 0
+}
+
+// compareBooks orders two books by keys, following the direction of each.
+func compareBooks(a, b models.Book, keys models.Sort) int {
+	for _, key := range keys {
+		cmp := compareKeys(bookKey(a, key.Field), bookKey(b, key.Field))
+		if key.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// cursorBook turns a cursor into a book holding its key values, so that
+// books can be compared with it like with each other.
+func cursorBook(keys models.Sort, cursor models.Cursor) (models.Book, error) {
+	book := models.Book{ID: cursor.ID}
+	values := cursor.Keys
+	for _, key := range keys {
+		if key.Field == "id" {
+			continue
+		}
+		if len(values) == 0 {
+			return book, errInvalidCursor
+		}
+		value, ok := cursorValue(key.Field, values[0])
+		values = values[1:]
+		if !ok {
+			return book, errInvalidCursor
+		}
+		switch key.Field {
+		case "name":
+			book.Name = value.(string)
+		case "genre":
+			book.Genre = value.(int)
+		case "price":
+			book.Price = value.(float64)
+		case "amount":
+			book.Amount = value.(int)
+		case "rank":
+			book.Rank = value.(float64)
+		case "created_at":
+			book.CreatedAt = value.(time.Time)
+		}
+	}
+	if len(values) != 0 {
+		return book, errInvalidCursor
+	}
+	return book, nil
+}
+
+// withAuthors returns book with its author summaries, sorted by name.
+func (m *MemoryDatabase) withAuthors(book models.Book) models.Book {
+	book.Authors = nil
+	for _, id := range m.bookAuthors[book.ID] {
+		author := m.authors[id]
+		book.Authors = append(book.Authors, models.AuthorSummary{ID: author.ID, Name: author.Name})
+	}
+	sort.SliceStable(book.Authors, func(i, j int) bool {
+		return book.Authors[i].Name < book.Authors[j].Name
+	})
+	book.Available = book.Amount > 0
+	return book
+}
+
+func (m *MemoryDatabase) CreateBook(ctx context.Context, book *models.Book) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, err := m.insertBook(book)
+	if err != nil {
+		book.ID = 0
+		return 0, err
+	}
+	book.ID = id
+	return id, nil
+}
+
+func (m *MemoryDatabase) insertBook(book *models.Book) (int, error) {
+	authors, err := m.checkBook(0, *book)
+	if err != nil {
+		return 0, err
+	}
+	m.lastBookID++
+	now := m.now().UTC()
+	stored := models.Book{
+		ID: m.lastBookID, Name: book.Name, ISBN: book.ISBN, Genre: book.Genre, Price: book.Price, Amount: book.Amount,
+		Version: 1, CreatedAt: now, UpdatedAt: now,
+	}
+	m.books[stored.ID] = stored
+	m.bookAuthors[stored.ID] = authors
+	book.CreatedAt, book.UpdatedAt = now, now
+	return stored.ID, nil
+}
+
+// checkBook applies the constraints of the books table to book, which is
+// stored under id or is new if id is 0, and returns its distinct author ids.
+func (m *MemoryDatabase) checkBook(id int, book models.Book) ([]int, error) {
+	for _, other := range m.books {
+		if other.ID == id {
+			continue
+		}
+		if other.Name == book.Name {
+			return nil, &ConflictError{Message: constraintMessages["books_name_key"]}
+		}
+		if book.ISBN != "" && other.ISBN == book.ISBN {
+			return nil, &ConflictError{Message: constraintMessages["books_isbn_key"]}
+		}
+	}
+	if _, ok := m.genres[book.Genre]; !ok {
+		return nil, &ValidationError{Message: constraintMessages["books_genre_fkey"]}
+	}
+	var authors []int
+	seen := map[int]bool{}
+	for _, author := range book.Authors {
+		if _, ok := m.authors[author.ID]; !ok {
+			return nil, &ValidationError{Message: constraintMessages["book_authors_author_id_fkey"]}
+		}
+		if !seen[author.ID] {
+			seen[author.ID] = true
+			authors = append(authors, author.ID)
+		}
+	}
+	return authors, nil
+}
+
+func (m *MemoryDatabase) UpsertBook(ctx context.Context, book *models.Book) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.books {
+		if existing.Name != book.Name {
+			continue
+		}
+		update := existing
+		update.Genre, update.Price, update.Amount = book.Genre, book.Price, book.Amount
+		if book.ISBN != "" {
+			update.ISBN = book.ISBN
+		}
+		update.Authors = nil
+		if _, err := m.checkBook(existing.ID, update); err != nil {
+			book.ID = 0
+			return false, err
+		}
+		update.Version++
+		update.UpdatedAt = m.now().UTC()
+		m.books[existing.ID] = update
+		book.ID = existing.ID
+		return false, nil
+	}
+	// Like ON CONFLICT, an upsert doesn't assign authors.
+	create := *book
+	create.Authors = nil
+	id, err := m.insertBook(&create)
+	if err != nil {
+		book.ID = 0
+		return false, err
+	}
+	book.ID = id
+	return true, nil
+}
+
+func (m *MemoryDatabase) GetBookByID(ctx context.Context, bookId int) (models.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	book, ok := m.books[bookId]
+	if !ok {
+		return models.Book{}, ErrNoMatch
+	}
+	return m.withAuthors(book), nil
+}
+
+func (m *MemoryDatabase) GetBookByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Book{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, book := range m.books {
+		if book.ISBN != "" && book.ISBN == isbn {
+			return m.withAuthors(book), nil
+		}
+	}
+	return models.Book{}, ErrNoMatch
+}
+
+func (m *MemoryDatabase) DeleteBookByID(ctx context.Context, bookId int, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteBook(bookId, version)
+}
+
+func (m *MemoryDatabase) deleteBook(bookId int, version int) error {
+	book, ok := m.books[bookId]
+	if !ok {
+		return ErrNoMatch
+	}
+	if version > 0 && book.Version != version {
+		return ErrVersionMismatch
+	}
+	delete(m.books, bookId)
+	delete(m.bookAuthors, bookId)
+	return nil
+}
+
+func (m *MemoryDatabase) UpdateBookByID(ctx context.Context, bookId int, bookData models.Book) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateBook(bookId, bookData)
+}
+
+func (m *MemoryDatabase) updateBook(bookId int, bookData models.Book) (int, error) {
+	book, ok := m.books[bookId]
+	if !ok {
+		return 0, ErrNoMatch
+	}
+	if bookData.Version > 0 && book.Version != bookData.Version {
+		return 0, ErrVersionMismatch
+	}
+	authors, err := m.checkBook(bookId, bookData)
+	if err != nil {
+		return 0, err
+	}
+	book.Name, book.Genre = bookData.Name, bookData.Genre
+	if bookData.ISBN != "" || bookData.ClearISBN {
+		book.ISBN = bookData.ISBN
+	}
+	book.Price, book.Amount = bookData.Price, bookData.Amount
+	book.Version++
+	book.UpdatedAt = m.now().UTC()
+	m.books[bookId] = book
+	m.bookAuthors[bookId] = authors
+	return bookId, nil
+}
+
+// BatchBooks works like Database.BatchBooks. An atomic batch runs against a
+// copy of the data that only replaces it when every operation succeeded.
+func (m *MemoryDatabase) BatchBooks(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(operations))
+	for i, operation := range operations {
+		results[i].Op = operation.Op
+		results[i].ID = operation.ID
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	original := m.memoryData
+	if atomic {
+		m.memoryData = original.clone()
+	}
+	for i, operation := range operations {
+		id, err := m.runOperation(operation)
+		if err != nil {
+			results[i].Err = err
+			if atomic {
+				m.memoryData = original
+				return results, nil
+			}
+			continue
+		}
+		results[i].ID = id
+	}
+	return results, nil
+}
+
+func (m *MemoryDatabase) runOperation(operation models.BatchOperation) (int, error) {
+	switch operation.Op {
+	case models.BatchCreate:
+		return m.insertBook(operation.Book)
+	case models.BatchUpdate:
+		book := *operation.Book
+		book.Version = operation.Version
+		return m.updateBook(operation.ID, book)
+	case models.BatchDelete:
+		return operation.ID, m.deleteBook(operation.ID, operation.Version)
+	default:
+		return 0, fmt.Errorf("unknown batch operation %q", operation.Op)
+	}
+}
+
+// ExportBooks calls fn for every book matching filter in id order. fn runs
+// on a snapshot, so it may take as long as it needs without blocking writes.
+func (m *MemoryDatabase) ExportBooks(ctx context.Context, filter models.BookFilter, fn func(book models.Book, genre string) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := (&queryBuilder{}).filterBooks(filter); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	books := m.filterBooks(filter)
+	genres := make(map[int]string, len(m.genres))
+	for id, genre := range m.genres {
+		genres[id] = genre.Name
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	for _, book := range books {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		book.Rank, book.Highlight = 0, ""
+		book.Available = book.Amount > 0
+		if err := fn(book, genres[book.Genre]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryDatabase) GetAllGenres(ctx context.Context) (*models.GenreList, error) {
+	list := &models.GenreList{}
+	if err := ctx.Err(); err != nil {
+		return list, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, genre := range m.genres {
+		list.Genres = append(list.Genres, genre)
+	}
+	sort.Slice(list.Genres, func(i, j int) bool { return list.Genres[i].ID < list.Genres[j].ID })
+	return list, nil
+}
+
+func (m *MemoryDatabase) GetGenreByID(ctx context.Context, genreId int) (models.Genre, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Genre{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	genre, ok := m.genres[genreId]
+	if !ok {
+		return models.Genre{}, ErrNoMatch
+	}
+	return genre, nil
+}
+
+func (m *MemoryDatabase) CreateGenre(ctx context.Context, genre *models.Genre) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkGenreName(0, genre.Name); err != nil {
+		return 0, err
+	}
+	m.lastGenreID++
+	genre.ID = m.lastGenreID
+	m.genres[genre.ID] = models.Genre{ID: genre.ID, Name: genre.Name}
+	return genre.ID, nil
+}
+
+func (m *MemoryDatabase) UpdateGenreByID(ctx context.Context, genreId int, genreData models.Genre) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.genres[genreId]; !ok {
+		return 0, ErrNoMatch
+	}
+	if err := m.checkGenreName(genreId, genreData.Name); err != nil {
+		return 0, err
+	}
+	m.genres[genreId] = models.Genre{ID: genreId, Name: genreData.Name}
+	return genreId, nil
+}
+
+func (m *MemoryDatabase) checkGenreName(id int, name string) error {
+	for _, genre := range m.genres {
+		if genre.ID != id && genre.Name == name {
+			return &ConflictError{Message: constraintMessages["genres_name_key"]}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryDatabase) DeleteGenreByID(ctx context.Context, genreId int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.genres[genreId]; !ok {
+		return ErrNoMatch
+	}
+	for _, book := range m.books {
+		if book.Genre == genreId {
+			return ErrGenreInUse
+		}
+	}
+	delete(m.genres, genreId)
+	return nil
+}
+
+func (m *MemoryDatabase) GetAllAuthors(ctx context.Context) (*models.AuthorList, error) {
+	list := &models.AuthorList{}
+	if err := ctx.Err(); err != nil {
+		return list, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, author := range m.authors {
+		list.Authors = append(list.Authors, author)
+	}
+	sort.Slice(list.Authors, func(i, j int) bool { return list.Authors[i].ID < list.Authors[j].ID })
+	return list, nil
+}
+
+func (m *MemoryDatabase) GetAuthorByID(ctx context.Context, authorId int) (models.Author, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Author{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	author, ok := m.authors[authorId]
+	if !ok {
+		return models.Author{}, ErrNoMatch
+	}
+	return author, nil
+}
+
+func (m *MemoryDatabase) CreateAuthor(ctx context.Context, author *models.Author) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastAuthorID++
+	author.ID = m.lastAuthorID
+	m.authors[author.ID] = models.Author{ID: author.ID, Name: author.Name}
+	return author.ID, nil
+}
+
+func (m *MemoryDatabase) UpdateAuthorByID(ctx context.Context, authorId int, authorData models.Author) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.authors[authorId]; !ok {
+		return 0, ErrNoMatch
+	}
+	m.authors[authorId] = models.Author{ID: authorId, Name: authorData.Name}
+	return authorId, nil
+}
+
+func (m *MemoryDatabase) DeleteAuthorByID(ctx context.Context, authorId int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.authors[authorId]; !ok {
+		return ErrNoMatch
+	}
+	for _, authors := range m.bookAuthors {
+		for _, id := range authors {
+			if id == authorId {
+				return ErrAuthorInUse
+			}
+		}
+	}
+	delete(m.authors, authorId)
+	return nil
+}
+
+// Ping always succeeds, there being no connection to lose.
+func (m *MemoryDatabase) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryDatabase) Close() error {
+	return nil
+}
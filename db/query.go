@@ -0,0 +1,320 @@
+package db
+
+import (
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// bookFilterColumns maps the fields of a models.Condition to the SQL they
+// are compared with. Only fields listed here can reach a query.
+var bookFilterColumns = map[string]string{
+	"name":   "name",
+	"isbn":   "isbn",
+	"genre":  "genre",
+	"price":  "price",
+	"amount": "amount",
+	"author": "id IN (SELECT book_id FROM book_authors WHERE author_id %s)",
+}
+
+var comparisons = map[models.Operator]string{
+	models.OpGt:  ">",
+	models.OpGte: ">=",
+	models.OpLt:  "<",
+	models.OpLte: "<=",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// dialect is the flavour of SQL a query is built for.
+type dialect int
+
+const (
+	dialectPostgres dialect = iota
+	dialectSQLite
+)
+
+// queryBuilder collects the conditions of a WHERE clause together with
+// their arguments, numbering placeholders as they are added.
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+	dialect    dialect
+}
+
+// arg adds an argument and returns its placeholder.
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *queryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+// whereClause returns the collected conditions joined with AND, or an empty
+// string if there are none.
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// filterBooks adds the availability and conditions of filter. Searching is
+// left to the caller, since it changes the shape of the query, but a search
+// without any words is rejected here.
+func (b *queryBuilder) filterBooks(filter models.BookFilter) error {
+	if filter.Search != "" && len(searchWords(filter.Search)) == 0 {
+		return errEmptySearch
+	}
+	switch filter.Availability {
+	case models.AvailabilityAll:
+	case models.AvailabilityOutOfStock:
+		b.where("amount <= 0")
+	default:
+		b.where("amount > 0")
+	}
+	for _, condition := range filter.Conditions {
+		if err := b.condition(condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// search adds a condition matching book names against text and returns the
+// tsquery and raw text expressions, for ranking and highlighting. Words are
+// matched by prefix through the search column, and misspelled ones through
+// trigram similarity.
+func (b *queryBuilder) search(text string) (tsquery string, raw string) {
+	tsquery = fmt.Sprintf("to_tsquery('simple', %s)", b.arg(prefixQuery(text)))
+	raw = b.arg(text)
+	b.where(fmt.Sprintf("(search @@ %s OR %s <%% name)", tsquery, raw))
+	return tsquery, raw
+}
+
+// prefixQuery turns free text into a tsquery matching every word as a
+// prefix, e.g. "the hobb" becomes "the:* & hobb:*". Anything but letters and
+// digits is dropped so the text can't inject tsquery operators.
+func prefixQuery(text string) string {
+	words := searchWords(text)
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// searchWords lowercases text and splits it into words of letters and
+// digits.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// condition adds a filter condition. Column names come from
+// bookFilterColumns and values are always passed as arguments.
+func (b *queryBuilder) condition(c models.Condition) error {
+	column, ok := bookFilterColumns[c.Field]
+	if !ok || len(c.Values) == 0 {
+		return fmt.Errorf("can't filter books by %s", c.Field)
+	}
+	var comparison string
+	switch c.Op {
+	case models.OpEq:
+		if len(c.Values) == 1 {
+			comparison = "= " + b.arg(c.Values[0])
+			break
+		}
+		placeholders := make([]string, len(c.Values))
+		for i, value := range c.Values {
+			placeholders[i] = b.arg(value)
+		}
+		comparison = "IN (" + strings.Join(placeholders, ", ") + ")"
+	case models.OpContains:
+		pattern := b.arg("%" + likeEscaper.Replace(fmt.Sprint(c.Values[0])) + "%")
+		if b.dialect == dialectSQLite {
+			// SQLite's LIKE already ignores case, but has no escape character
+			// by default.
+			comparison = "LIKE " + pattern + ` ESCAPE '\'`
+		} else {
+			comparison = "ILIKE " + pattern
+		}
+	default:
+		operator, ok := comparisons[c.Op]
+		if !ok {
+			return fmt.Errorf("can't filter books with %s", c.Op)
+		}
+		comparison = operator + " " + b.arg(c.Values[0])
+	}
+	if strings.Contains(column, "%s") {
+		b.where(fmt.Sprintf(column, comparison))
+	} else {
+		b.where(column + " " + comparison)
+	}
+	return nil
+}
+
+// bookSortColumns maps the fields of a models.SortKey to the SQL they are
+// sorted by. rank only exists in search queries.
+var bookSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"genre":      "genre",
+	"price":      "price",
+	"amount":     "amount",
+	"created_at": "created_at",
+	"rank":       "rank",
+}
+
+var errInvalidCursor = models.ValidationErrors{{Field: "cursor", Message: "invalid cursor"}}
+
+var errEmptySearch = models.ValidationErrors{{Field: "q", Message: "q has to contain a letter or digit"}}
+
+// bookOrder returns the keys a book list is sorted by: the requested ones,
+// or relevance when searching, always followed by id to make the order total.
+func bookOrder(sort models.Sort, searching bool) models.Sort {
+	keys := append(models.Sort{}, sort...)
+	if len(keys) == 0 && searching {
+		keys = append(keys, models.SortKey{Field: "rank", Desc: true})
+	}
+	for _, key := range keys {
+		if key.Field == "id" {
+			return keys
+		}
+	}
+	return append(keys, models.SortKey{Field: "id", Desc: true})
+}
+
+func orderBy(keys models.Sort) (string, error) {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		column, ok := bookSortColumns[key.Field]
+		if !ok {
+			return "", fmt.Errorf("can't sort books by %s", key.Field)
+		}
+		parts[i] = column + " ASC"
+		if key.Desc {
+			parts[i] = column + " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(parts, ", "), nil
+}
+
+// after adds a condition keeping the rows that come after cursor in the
+// order given by keys. The cursor has to be made by nextCursor for the same
+// keys.
+func (b *queryBuilder) after(keys models.Sort, cursor models.Cursor) error {
+	values := cursor.Keys
+	placeholders := make([]string, len(keys))
+	for i, key := range keys {
+		if key.Field == "id" {
+			placeholders[i] = b.arg(cursor.ID)
+			continue
+		}
+		if len(values) == 0 {
+			return errInvalidCursor
+		}
+		value, ok := cursorValue(key.Field, values[0])
+		if !ok {
+			return errInvalidCursor
+		}
+		if t, isTime := value.(time.Time); isTime && b.dialect == dialectSQLite {
+			// Timestamps are stored as text there, so they have to be
+			// compared in the same format.
+			value = sqliteTime(t)
+		}
+		placeholders[i] = b.arg(value)
+		values = values[1:]
+	}
+	if len(values) != 0 {
+		return errInvalidCursor
+	}
+
+	// (a, b) after (x, y) is a > x OR (a = x AND b > y), with the comparison
+	// of every key following its direction.
+	var alternatives []string
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, bookSortColumns[keys[j].Field]+" = "+placeholders[j])
+		}
+		operator := " > "
+		if key.Desc {
+			operator = " < "
+		}
+		parts = append(parts, bookSortColumns[key.Field]+operator+placeholders[i])
+		if len(parts) == 1 {
+			alternatives = append(alternatives, parts[0])
+		} else {
+			alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+		}
+	}
+	if len(alternatives) == 1 {
+		b.where(alternatives[0])
+	} else {
+		b.where("(" + strings.Join(alternatives, " OR ") + ")")
+	}
+	return nil
+}
+
+// cursorValue checks that value, decoded from a client's cursor, has the type
+// of field, and returns it converted to that type.
+func cursorValue(field string, value interface{}) (interface{}, bool) {
+	switch field {
+	case "name", "isbn":
+		text, ok := value.(string)
+		return text, ok
+	case "price", "rank":
+		switch v := value.(type) {
+		case float64:
+			return v, true
+		case int:
+			return float64(v), true
+		}
+	case "genre", "amount", "version":
+		switch v := value.(type) {
+		case int:
+			return v, true
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+				return int(v), true
+			}
+		}
+	case "created_at":
+		switch v := value.(type) {
+		case time.Time:
+			return v, true
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			return t, err == nil
+		}
+	}
+	return nil, false
+}
+
+// nextCursor points at book in a list sorted by keys.
+func nextCursor(book models.Book, keys models.Sort, sort models.Sort) models.Cursor {
+	cursor := models.Cursor{ID: book.ID, Sort: sort.String()}
+	for _, key := range keys {
+		switch key.Field {
+		case "name":
+			cursor.Keys = append(cursor.Keys, book.Name)
+		case "genre":
+			cursor.Keys = append(cursor.Keys, book.Genre)
+		case "price":
+			cursor.Keys = append(cursor.Keys, book.Price)
+		case "amount":
+			cursor.Keys = append(cursor.Keys, book.Amount)
+		case "created_at":
+			cursor.Keys = append(cursor.Keys, book.CreatedAt)
+		case "rank":
+			cursor.Keys = append(cursor.Keys, book.Rank)
+		}
+	}
+	return cursor
+}
@@ -0,0 +1,482 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteTimeLayout is how SQLite stores timestamps: fixed width UTC text,
+// which sorts like the times themselves and which the driver reads back
+// into time.Time.
+const sqliteTimeLayout = "2006-01-02 15:04:05.000000000-07:00"
+
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// SQLiteDatabase is a DatabaseBooksManager keeping the catalog in a SQLite
+// file, for deployments without a Postgres server. It behaves like
+// Database, except that search doesn't match misspelled words.
+type SQLiteDatabase struct {
+	Conn *sql.DB
+	// QueryTimeout bounds every repository call. Zero means calls are only
+	// limited by the caller's context.
+	QueryTimeout time.Duration
+}
+
+// NewSQLiteDatabase opens the SQLite database at path, creating it if
+// needed. Its schema is left to migrations.Runner. ":memory:" opens a
+// private database that lives as long as the returned value.
+func NewSQLiteDatabase(path string) (SQLiteDatabase, error) {
+	db := SQLiteDatabase{}
+	// Writers take the lock when their transaction starts, so they queue on
+	// busy_timeout instead of failing halfway through.
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return db, err
+	}
+	if path == ":memory:" {
+		// Every connection would get a database of its own.
+		conn.SetMaxOpenConns(1)
+	}
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return db, err
+	}
+	db.Conn = conn
+	return db, nil
+}
+
+// sqliteUniqueColumns maps the columns SQLite names in unique violations to
+// the constraints Postgres would name.
+var sqliteUniqueColumns = map[string]string{
+	"books.name":  "books_name_key",
+	"books.isbn":  "books_isbn_key",
+	"genres.name": "genres_name_key",
+}
+
+// translateSQLiteError is translateError for SQLite. SQLite doesn't say
+// which foreign key failed, so the caller names the one its statement
+// can break.
+func translateSQLiteError(ctx context.Context, err error, foreignKey string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoMatch
+	}
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	code := sqliteErr.Code()
+	if code == sqlite3.SQLITE_ERROR && strings.Contains(sqliteErr.Error(), "FOREIGN KEY constraint failed") {
+		// Statements with RETURNING report broken foreign keys as plain
+		// errors.
+		code = sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	}
+	switch code {
+	case sqlite3.SQLITE_INTERRUPT:
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%w: %s", ctxErr, sqliteErr.Error())
+		}
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		message := sqliteErr.Error()
+		for column, constraint := range sqliteUniqueColumns {
+			if strings.Contains(message, "failed: "+column+" ") {
+				message = constraintMessages[constraint]
+			}
+		}
+		return &ConflictError{Message: message, Err: err}
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		message, ok := constraintMessages[foreignKey]
+		if !ok {
+			message = sqliteErr.Error()
+		}
+		return &ValidationError{Message: message, Err: err}
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK:
+		return &ValidationError{Message: sqliteErr.Error(), Err: err}
+	}
+	return err
+}
+
+// sqliteSearchQuery turns free text into an FTS5 query matching every word
+// as a prefix, e.g. "the hobb" becomes `"the"* "hobb"*`.
+func sqliteSearchQuery(text string) string {
+	words := searchWords(text)
+	for i, word := range words {
+		words[i] = `"` + word + `"*`
+	}
+	if len(words) == 0 {
+		// An empty phrase is valid and matches nothing.
+		return `""`
+	}
+	return strings.Join(words, " ")
+}
+
+func (db SQLiteDatabase) GetAllBooks(ctx context.Context, filter models.BookFilter, page models.Page) (*models.BookList, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	list := &models.BookList{}
+	builder := &queryBuilder{dialect: dialectSQLite}
+	if err := builder.filterBooks(filter); err != nil {
+		return list, err
+	}
+	if page.Limit <= 0 {
+		page.Limit = models.DefaultPageLimit
+	}
+
+	searching := filter.Search != ""
+	keys := bookOrder(page.Sort, searching)
+	if !page.Cursor.IsZero() && page.Cursor.Sort != page.Sort.String() {
+		return list, errInvalidCursor
+	}
+	order, err := orderBy(keys)
+	if err != nil {
+		return list, err
+	}
+	query := "SELECT " + bookColumns + " FROM books"
+	if searching {
+		query = "SELECT " + bookColumns + ", rank, highlight FROM books JOIN (" +
+			"SELECT rowid AS match_id, -bm25(books_search) AS rank" +
+			", highlight(books_search, 0, '<mark>', '</mark>') AS highlight" +
+			" FROM books_search WHERE books_search MATCH " + builder.arg(sqliteSearchQuery(filter.Search)) +
+			") ON match_id = id"
+	}
+	if !page.Cursor.IsZero() {
+		if err := builder.after(keys, page.Cursor); err != nil {
+			return list, err
+		}
+	}
+	// One extra row tells us whether another page exists.
+	query += builder.whereClause() + order + " LIMIT " + builder.arg(page.Limit+1)
+
+	rows, err := db.Conn.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return list, translateSQLiteError(ctx, err, "")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var book models.Book
+		dest := bookFields(&book)
+		if searching {
+			dest = append(dest, &book.Rank, &book.Highlight)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return list, err
+		}
+		book.Available = book.Amount > 0
+		list.Books = append(list.Books, book)
+	}
+	if err := rows.Err(); err != nil {
+		return list, translateSQLiteError(ctx, err, "")
+	}
+	if len(list.Books) > page.Limit {
+		list.Books = list.Books[:page.Limit]
+		list.HasMore = true
+		list.NextCursor = nextCursor(list.Books[page.Limit-1], keys, page.Sort).Encode()
+	}
+	if err := sqliteLoadAuthors(ctx, db.Conn, list.Books); err != nil {
+		return list, err
+	}
+	return list, nil
+}
+
+func (db SQLiteDatabase) CreateBook(ctx context.Context, book *models.Book) (int, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := sqliteInsertBook(ctx, tx, book)
+	if err != nil {
+		book.ID = 0
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		book.ID = 0
+		return 0, err
+	}
+	book.ID = id
+	return id, nil
+}
+
+func sqliteInsertBook(ctx context.Context, tx *sql.Tx, book *models.Book) (int, error) {
+	now := time.Now().UTC()
+	var id int
+	query := `INSERT INTO books (name, genre, price, amount, isbn, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $6) RETURNING id`
+	err := tx.QueryRowContext(ctx, query, book.Name, book.Genre, book.Price, book.Amount, book.ISBN, sqliteTime(now)).
+		Scan(&id)
+	if err != nil {
+		return 0, translateSQLiteError(ctx, err, "books_genre_fkey")
+	}
+	book.CreatedAt, book.UpdatedAt = now, now
+	if err := sqliteAddBookAuthors(ctx, tx, id, book.Authors); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpsertBook creates a book or, if one with the same name exists, overwrites
+// its genre, price and amount, and its ISBN if book has one. Authors of an
+// existing book are kept.
+func (db SQLiteDatabase) UpsertBook(ctx context.Context, book *models.Book) (bool, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	created, err := db.upsertBook(ctx, book)
+	if err != nil {
+		book.ID = 0
+		return false, err
+	}
+	return created, nil
+}
+
+func (db SQLiteDatabase) upsertBook(ctx context.Context, book *models.Book) (bool, error) {
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	created := false
+	err = tx.QueryRowContext(ctx, `SELECT id FROM books WHERE name = $1`, book.Name).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		create := *book
+		create.Authors = nil
+		if id, err = sqliteInsertBook(ctx, tx, &create); err != nil {
+			return false, err
+		}
+		created = true
+	case err != nil:
+		return false, translateSQLiteError(ctx, err, "")
+	default:
+		query := `UPDATE books SET genre=$1, price=$2, amount=$3, isbn=COALESCE(NULLIF($4, ''), isbn),
+			version=version+1, updated_at=$5 WHERE id=$6`
+		_, err := tx.ExecContext(ctx, query, book.Genre, book.Price, book.Amount, book.ISBN, sqliteTime(time.Now()), id)
+		if err != nil {
+			return false, translateSQLiteError(ctx, err, "books_genre_fkey")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	book.ID = id
+	return created, nil
+}
+
+func (db SQLiteDatabase) GetBookByID(ctx context.Context, bookId int) (models.Book, error) {
+	return db.getBook(ctx, "id", bookId)
+}
+
+// GetBookByISBN finds a book by its normalized ISBN-13.
+func (db SQLiteDatabase) GetBookByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	return db.getBook(ctx, "isbn", isbn)
+}
+
+// getBook finds a book by a unique column.
+func (db SQLiteDatabase) getBook(ctx context.Context, column string, value interface{}) (models.Book, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	book := models.Book{}
+	query := `SELECT ` + bookColumns + ` FROM books WHERE ` + column + ` = $1;`
+	err := db.Conn.QueryRowContext(ctx, query, value).Scan(bookFields(&book)...)
+	if err != nil {
+		return book, translateSQLiteError(ctx, err, "")
+	}
+	book.Available = book.Amount > 0
+	books := []models.Book{book}
+	if err := sqliteLoadAuthors(ctx, db.Conn, books); err != nil {
+		return book, err
+	}
+	return books[0], nil
+}
+
+// DeleteBookByID removes a book. A non-zero version makes the delete
+// conditional on the book still being at that version.
+func (db SQLiteDatabase) DeleteBookByID(ctx context.Context, bookId int, version int) error {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := sqliteDeleteBook(ctx, tx, bookId, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func sqliteDeleteBook(ctx context.Context, tx *sql.Tx, bookId int, version int) error {
+	query := `DELETE FROM books WHERE id = $1;`
+	args := []interface{}{bookId}
+	if version > 0 {
+		query = `DELETE FROM books WHERE id = $1 AND version = $2;`
+		args = append(args, version)
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return translateSQLiteError(ctx, err, "")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return missingBookError(ctx, tx, bookId, version)
+	}
+	return nil
+}
+
+// UpdateBookByID overwrites a book. A non-zero bookData.Version makes the
+// update conditional on the book still being at that version.
+func (db SQLiteDatabase) UpdateBookByID(ctx context.Context, bookId int, bookData models.Book) (int, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	newBookID, err := sqliteUpdateBook(ctx, tx, bookId, bookData)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return newBookID, nil
+}
+
+func sqliteUpdateBook(ctx context.Context, tx *sql.Tx, bookId int, bookData models.Book) (int, error) {
+	query := `UPDATE books SET name=$1, genre=$2, price=$3, amount=$4, isbn=` + updatedISBN(bookData) + `,
+		version=version+1, updated_at=$6 WHERE id=$7`
+	args := []interface{}{bookData.Name, bookData.Genre, bookData.Price, bookData.Amount, bookData.ISBN,
+		sqliteTime(time.Now()), bookId}
+	if bookData.Version > 0 {
+		query += ` AND version=$8`
+		args = append(args, bookData.Version)
+	}
+	var newBookID int
+	err := tx.QueryRowContext(ctx, query+` RETURNING id;`, args...).Scan(&newBookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, missingBookError(ctx, tx, bookId, bookData.Version)
+	}
+	if err != nil {
+		return 0, translateSQLiteError(ctx, err, "books_genre_fkey")
+	}
+	if err := sqliteReplaceBookAuthors(ctx, tx, newBookID, bookData.Authors); err != nil {
+		return 0, err
+	}
+	return newBookID, nil
+}
+
+// BatchBooks works like Database.BatchBooks. SQLite hands out ids inside the
+// transaction, so the ones taken by a failed atomic batch are reserved in the
+// same transaction, keeping them from being reused as a Postgres sequence
+// does.
+func (db SQLiteDatabase) BatchBooks(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error) {
+	return runBatch(ctx, db.Conn, operations, atomic, sqliteRunOperation, sqliteReserveBookIDs)
+}
+
+// sqliteReserveBookIDs keeps AUTOINCREMENT from handing out the ids of books
+// created by a rolled back batch again.
+func sqliteReserveBookIDs(ctx context.Context, tx *sql.Tx, results []models.BatchResult) error {
+	lastID := 0
+	for _, result := range results {
+		if result.Op == models.BatchCreate && result.Err == nil && result.ID > lastID {
+			lastID = result.ID
+		}
+	}
+	if lastID == 0 {
+		return nil
+	}
+	result, err := tx.ExecContext(ctx, `UPDATE sqlite_sequence SET seq = MAX(seq, $1) WHERE name = 'books'`, lastID)
+	if err != nil {
+		return translateSQLiteError(ctx, err, "")
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO sqlite_sequence (name, seq) VALUES ('books', $1)`, lastID)
+	return translateSQLiteError(ctx, err, "")
+}
+
+func sqliteRunOperation(ctx context.Context, tx *sql.Tx, operation models.BatchOperation) (int, error) {
+	switch operation.Op {
+	case models.BatchCreate:
+		return sqliteInsertBook(ctx, tx, operation.Book)
+	case models.BatchUpdate:
+		book := *operation.Book
+		book.Version = operation.Version
+		return sqliteUpdateBook(ctx, tx, operation.ID, book)
+	case models.BatchDelete:
+		return operation.ID, sqliteDeleteBook(ctx, tx, operation.ID, operation.Version)
+	default:
+		return 0, fmt.Errorf("unknown batch operation %q", operation.Op)
+	}
+}
+
+// ExportBooks works like Database.ExportBooks.
+func (db SQLiteDatabase) ExportBooks(ctx context.Context, filter models.BookFilter, fn func(book models.Book, genre string) error) error {
+	builder := &queryBuilder{dialect: dialectSQLite}
+	if err := builder.filterBooks(filter); err != nil {
+		return err
+	}
+	if filter.Search != "" {
+		builder.where("id IN (SELECT rowid FROM books_search WHERE books_search MATCH " +
+			builder.arg(sqliteSearchQuery(filter.Search)) + ")")
+	}
+	query := "SELECT " + bookColumns + ", (SELECT name FROM genres WHERE genres.id = books.genre)" +
+		" FROM books" + builder.whereClause() + " ORDER BY id ASC"
+
+	rows, err := db.Conn.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return translateSQLiteError(ctx, err, "")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var book models.Book
+		var genre string
+		err := rows.Scan(append(bookFields(&book), &genre)...)
+		if err != nil {
+			return err
+		}
+		book.Available = book.Amount > 0
+		if err := fn(book, genre); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (db SQLiteDatabase) Ping(ctx context.Context) error {
+	return db.Conn.PingContext(ctx)
+}
+
+func (db SQLiteDatabase) Close() error {
+	return db.Conn.Close()
+}
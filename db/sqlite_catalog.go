@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func (db SQLiteDatabase) GetAllGenres(ctx context.Context) (*models.GenreList, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	list := &models.GenreList{}
+	rows, err := db.Conn.QueryContext(ctx, `SELECT id, name FROM genres ORDER BY id`)
+	if err != nil {
+		return list, translateSQLiteError(ctx, err, "")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var genre models.Genre
+		if err := rows.Scan(&genre.ID, &genre.Name); err != nil {
+			return list, err
+		}
+		list.Genres = append(list.Genres, genre)
+	}
+	return list, rows.Err()
+}
+
+func (db SQLiteDatabase) GetGenreByID(ctx context.Context, genreId int) (models.Genre, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	genre := models.Genre{}
+	query := `SELECT id, name FROM genres WHERE id = $1;`
+	err := db.Conn.QueryRowContext(ctx, query, genreId).Scan(&genre.ID, &genre.Name)
+	return genre, translateSQLiteError(ctx, err, "")
+}
+
+func (db SQLiteDatabase) CreateGenre(ctx context.Context, genre *models.Genre) (int, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	var id int
+	query := `INSERT INTO genres (name) VALUES ($1) RETURNING id`
+	if err := db.Conn.QueryRowContext(ctx, query, genre.Name).Scan(&id); err != nil {
+		return 0, translateSQLiteError(ctx, err, "")
+	}
+	genre.ID = id
+	return id, nil
+}
+
+func (db SQLiteDatabase) UpdateGenreByID(ctx context.Context, genreId int, genreData models.Genre) (int, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	var id int
+	query := `UPDATE genres SET name=$1 WHERE id=$2 RETURNING id;`
+	if err := db.Conn.QueryRowContext(ctx, query, genreData.Name, genreId).Scan(&id); err != nil {
+		return 0, translateSQLiteError(ctx, err, "")
+	}
+	return id, nil
+}
+
+func (db SQLiteDatabase) DeleteGenreByID(ctx context.Context, genreId int) error {
+	return db.deleteReferenced(ctx, `DELETE FROM genres WHERE id = $1;`, genreId, ErrGenreInUse)
+}
+
+func (db SQLiteDatabase) GetAllAuthors(ctx context.Context) (*models.AuthorList, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	list := &models.AuthorList{}
+	rows, err := db.Conn.QueryContext(ctx, `SELECT id, name FROM authors ORDER BY id`)
+	if err != nil {
+		return list, translateSQLiteError(ctx, err, "")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var author models.Author
+		if err := rows.Scan(&author.ID, &author.Name); err != nil {
+			return list, err
+		}
+		list.Authors = append(list.Authors, author)
+	}
+	return list, rows.Err()
+}
+
+func (db SQLiteDatabase) GetAuthorByID(ctx context.Context, authorId int) (models.Author, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	author := models.Author{}
+	query := `SELECT id, name FROM authors WHERE id = $1;`
+	err := db.Conn.QueryRowContext(ctx, query, authorId).Scan(&author.ID, &author.Name)
+	return author, translateSQLiteError(ctx, err, "")
+}
+
+func (db SQLiteDatabase) CreateAuthor(ctx context.Context, author *models.Author) (int, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	var id int
+	query := `INSERT INTO authors (name) VALUES ($1) RETURNING id`
+	if err := db.Conn.QueryRowContext(ctx, query, author.Name).Scan(&id); err != nil {
+		return 0, translateSQLiteError(ctx, err, "")
+	}
+	author.ID = id
+	return id, nil
+}
+
+func (db SQLiteDatabase) UpdateAuthorByID(ctx context.Context, authorId int, authorData models.Author) (int, error) {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	var id int
+	query := `UPDATE authors SET name=$1 WHERE id=$2 RETURNING id;`
+	if err := db.Conn.QueryRowContext(ctx, query, authorData.Name, authorId).Scan(&id); err != nil {
+		return 0, translateSQLiteError(ctx, err, "")
+	}
+	return id, nil
+}
+
+func (db SQLiteDatabase) DeleteAuthorByID(ctx context.Context, authorId int) error {
+	return db.deleteReferenced(ctx, `DELETE FROM authors WHERE id = $1;`, authorId, ErrAuthorInUse)
+}
+
+// deleteReferenced runs a delete by id that fails with inUse while books
+// still reference the row.
+func (db SQLiteDatabase) deleteReferenced(ctx context.Context, query string, id int, inUse error) error {
+	ctx, cancel := withTimeout(ctx, db.QueryTimeout)
+	defer cancel()
+
+	result, err := db.Conn.ExecContext(ctx, query, id)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+			return inUse
+		}
+		return translateSQLiteError(ctx, err, "")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNoMatch
+	}
+	return nil
+}
+
+// sqliteLoadAuthors fills in the author summaries of the given books with a
+// single query.
+func sqliteLoadAuthors(ctx context.Context, conn *sql.DB, books []models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	builder := &queryBuilder{dialect: dialectSQLite}
+	placeholders := make([]string, len(books))
+	for i, book := range books {
+		placeholders[i] = builder.arg(book.ID)
+	}
+	query := `SELECT ba.book_id, a.id, a.name FROM book_authors ba
+		JOIN authors a ON a.id = ba.author_id
+		WHERE ba.book_id IN (` + strings.Join(placeholders, ", ") + `) ORDER BY a.name`
+	rows, err := conn.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return translateSQLiteError(ctx, err, "")
+	}
+	defer rows.Close()
+	authors := map[int][]models.AuthorSummary{}
+	for rows.Next() {
+		var bookID int
+		var author models.AuthorSummary
+		if err := rows.Scan(&bookID, &author.ID, &author.Name); err != nil {
+			return err
+		}
+		authors[bookID] = append(authors[bookID], author)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range books {
+		books[i].Authors = authors[books[i].ID]
+	}
+	return nil
+}
+
+// sqliteAddBookAuthors assigns authors to a book inside the given
+// transaction.
+func sqliteAddBookAuthors(ctx context.Context, tx *sql.Tx, bookID int, authors []models.AuthorSummary) error {
+	seen := map[int]bool{}
+	for _, author := range authors {
+		if seen[author.ID] {
+			continue
+		}
+		seen[author.ID] = true
+		query := `INSERT INTO book_authors (book_id, author_id) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, query, bookID, author.ID); err != nil {
+			return translateSQLiteError(ctx, err, "book_authors_author_id_fkey")
+		}
+	}
+	return nil
+}
+
+// sqliteReplaceBookAuthors drops the current authors of a book and assigns
+// the given ones inside the transaction.
+func sqliteReplaceBookAuthors(ctx context.Context, tx *sql.Tx, bookID int, authors []models.AuthorSummary) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = $1`, bookID); err != nil {
+		return translateSQLiteError(ctx, err, "")
+	}
+	return sqliteAddBookAuthors(ctx, tx, bookID, authors)
+}
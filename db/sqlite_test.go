@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"github.com/GlobantObrikosina/golang-rest-api/migrations"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openSQLite opens the SQLite database at path and migrates it.
+func openSQLite(t *testing.T, path string) SQLiteDatabase {
+	repo, err := NewSQLiteDatabase(path)
+	if err != nil {
+		t.Fatalf("Could not open database: %v", err)
+	}
+	runner, err := migrations.NewRunner(repo.Conn, migrations.SQLite)
+	if err != nil {
+		t.Fatalf("Could not load migrations: %v", err)
+	}
+	if _, err := runner.Up(context.Background()); err != nil {
+		t.Fatalf("Could not migrate database: %v", err)
+	}
+	return repo
+}
+
+func TestNewSQLiteDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.db")
+	repo := openSQLite(t, path)
+	_, err := repo.CreateBook(context.Background(), &models.Book{Name: "Emma", Genre: 2, Price: 8, Amount: 2})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close())
+
+	// Reopening finds the data still there.
+	repo, err = NewSQLiteDatabase(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer repo.Close()
+	book, err := repo.GetBookByID(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Emma", book.Name)
+	assert.WithinDuration(t, time.Now(), book.CreatedAt, time.Minute)
+}
+
+func TestSQLiteCursor(t *testing.T) {
+	tests := []struct {
+		name          string
+		keys          models.Sort
+		cursor        models.Cursor
+		expectedArgs  []interface{}
+		expectedError error
+	}{
+		{
+			name:         "Created at",
+			keys:         models.Sort{{Field: "created_at", Desc: true}, {Field: "id", Desc: true}},
+			cursor:       models.Cursor{ID: 3, Keys: []interface{}{"2021-03-01T12:00:00.5Z"}},
+			expectedArgs: []interface{}{"2021-03-01 12:00:00.500000000+00:00", 3},
+		},
+		{
+			name:         "Other keys",
+			keys:         models.Sort{{Field: "price"}, {Field: "id", Desc: true}},
+			cursor:       models.Cursor{ID: 3, Keys: []interface{}{12.5}},
+			expectedArgs: []interface{}{12.5, 3},
+		},
+		{
+			name:          "Not a time",
+			keys:          models.Sort{{Field: "created_at"}, {Field: "id", Desc: true}},
+			cursor:        models.Cursor{ID: 3, Keys: []interface{}{12.5}},
+			expectedError: errInvalidCursor,
+		},
+		{
+			name:          "Missing key",
+			keys:          models.Sort{{Field: "created_at"}, {Field: "id", Desc: true}},
+			cursor:        models.Cursor{ID: 3},
+			expectedError: errInvalidCursor,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := &queryBuilder{dialect: dialectSQLite}
+			err := builder.after(test.keys, test.cursor)
+			assert.Equal(t, test.expectedError, err)
+			if err == nil {
+				assert.Equal(t, test.expectedArgs, builder.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"net/http"
+	"strconv"
+)
+
+var authorIDKey = "authorID"
+
+func (h *Handler) authors(router chi.Router) {
+	router.Get("/", h.GetAllAuthors)
+	router.Post("/", h.CreateAuthor)
+	router.Route("/{authorID}", func(router chi.Router) {
+		router.Use(h.AuthorContext)
+		router.Get("/", h.GetAuthor)
+		router.Put("/", h.UpdateAuthor)
+		router.Delete("/", h.DeleteAuthorByID)
+	})
+}
+
+func (h *Handler) AuthorContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorID := chi.URLParam(r, "authorID")
+
+		id, err := strconv.Atoi(authorID)
+		if err != nil {
+			_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("invalid author ID")))
+			return
+		}
+		ctx := context.WithValue(r.Context(), authorIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *Handler) GetAllAuthors(w http.ResponseWriter, r *http.Request) {
+	authors, err := h.service.GetAllAuthors(r.Context())
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, authors); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+func (h *Handler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	author := &models.Author{}
+	if err := render.Bind(r, author); err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	id, err := h.service.CreateAuthor(r.Context(), author)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, models.CreateAuthorResponse{AuthorID: id}); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+func (h *Handler) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	authorID := r.Context().Value(authorIDKey).(int)
+	author, err := h.service.GetAuthorByID(r.Context(), authorID)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, &author); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+func (h *Handler) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	authorID := r.Context().Value(authorIDKey).(int)
+	authorData := models.Author{}
+	if err := render.Bind(r, &authorData); err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	id, err := h.service.UpdateAuthorByID(r.Context(), authorID, authorData)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, models.CreateAuthorResponse{AuthorID: id}); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+func (h *Handler) DeleteAuthorByID(w http.ResponseWriter, r *http.Request) {
+	authorID := r.Context().Value(authorIDKey).(int)
+	if err := h.service.DeleteAuthorByID(r.Context(), authorID); err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	render.NoContent(w, r)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthors(t *testing.T) {
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "List",
+			method: "GET",
+			target: "/authors/",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllAuthors(gomock.Any()).Return(&models.AuthorList{Authors: []models.Author{
+					{ID: 1, Name: "Jules Verne"},
+					{ID: 2, Name: "Mark Twain"},
+				}}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"authors\":[{\"id\":1,\"name\":\"Jules Verne\"},{\"id\":2,\"name\":\"Mark Twain\"}]}\n",
+		},
+		{
+			name:   "Get",
+			method: "GET",
+			target: "/authors/3",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAuthorByID(gomock.Any(), 3).Return(models.Author{ID: 3, Name: "J. R. R. Tolkien"}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":3,\"name\":\"J. R. R. Tolkien\"}\n",
+		},
+		{
+			name:                 "Get invalid id",
+			method:               "GET",
+			target:               "/authors/abc",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"invalid author ID\"}\n",
+		},
+		{
+			name:   "Get not found",
+			method: "GET",
+			target: "/authors/9",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAuthorByID(gomock.Any(), 9).Return(models.Author{}, db.ErrNoMatch)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
+		},
+		{
+			name:      "Create",
+			method:    "POST",
+			target:    "/authors/",
+			inputBody: `{"name": "Ursula K. Le Guin"}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().CreateAuthor(gomock.Any(), &models.Author{Name: "Ursula K. Le Guin"}).Return(4, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":4}\n",
+		},
+		{
+			name:                 "Create without name",
+			method:               "POST",
+			target:               "/authors/",
+			inputBody:            `{}`,
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"name is a required field\"}\n",
+		},
+		{
+			name:      "Rename",
+			method:    "PUT",
+			target:    "/authors/2",
+			inputBody: `{"name": "Samuel Clemens"}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().UpdateAuthorByID(gomock.Any(), 2, models.Author{Name: "Samuel Clemens"}).Return(2, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":2}\n",
+		},
+		{
+			name:   "Delete",
+			method: "DELETE",
+			target: "/authors/4",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().DeleteAuthorByID(gomock.Any(), 4).Return(nil)
+			},
+			expectedStatusCode:   http.StatusNoContent,
+			expectedResponseBody: ``,
+		},
+		{
+			name:   "Delete author with books",
+			method: "DELETE",
+			target: "/authors/1",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().DeleteAuthorByID(gomock.Any(), 1).Return(db.ErrAuthorInUse)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: "{\"status_text\":\"Conflict\",\"message\":\"author has books\"}\n",
+		},
+		{
+			name:   "List error",
+			method: "GET",
+			target: "/authors/",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllAuthors(gomock.Any()).Return(nil, errors.New("connection refused"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: "{\"status_text\":\"Internal server error\",\"message\":\"connection refused\"}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			services := service.NewService(mockManager)
+			handler := NewHandler(services)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
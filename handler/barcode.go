@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/barcode"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/go-chi/render"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBarcodeScale = 3
+	maxBarcodeScale     = 10
+)
+
+// GetBookBarcodeSVG draws the EAN-13 barcode of a book's ISBN as SVG.
+func (h *Handler) GetBookBarcodeSVG(w http.ResponseWriter, r *http.Request) {
+	h.writeBookBarcode(w, r, "image/svg+xml", barcode.EAN13.WriteSVG)
+}
+
+// GetBookBarcodePNG draws the EAN-13 barcode of a book's ISBN as PNG.
+func (h *Handler) GetBookBarcodePNG(w http.ResponseWriter, r *http.Request) {
+	h.writeBookBarcode(w, r, "image/png", barcode.EAN13.WritePNG)
+}
+
+// writeBookBarcode draws the barcode at the pixels per module given by the
+// scale parameter. The image is drawn before anything is written, so a
+// failure still gets an error response.
+func (h *Handler) writeBookBarcode(w http.ResponseWriter, r *http.Request, contentType string,
+	draw func(barcode.EAN13, io.Writer, int) error) {
+	scale := defaultBarcodeScale
+	if value := r.URL.Query().Get("scale"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxBarcodeScale {
+			_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("scale has to be between 1 and %d", maxBarcodeScale)))
+			return
+		}
+		scale = n
+	}
+	bookID := r.Context().Value(bookIDKey).(int)
+	book, err := h.service.GetBookByID(r.Context(), bookID)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	code, err := bookBarcode(book)
+	if err != nil {
+		_ = render.Render(w, r, NotFoundErrorRenderer(err))
+		return
+	}
+	var image bytes.Buffer
+	if err := draw(code, &image, scale); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = image.WriteTo(w)
+}
+
+// GetBookLabels is an SVG sheet of shelf labels for the books in the ids
+// parameter, e.g. ids=1,2,2 for one label of book 1 and two of book 2.
+func (h *Handler) GetBookLabels(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseLabelIDs(r.URL.Query().Get("ids"))
+	if err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	books := map[int]models.Book{}
+	labels := make([]barcode.Label, 0, len(ids))
+	for _, id := range ids {
+		book, ok := books[id]
+		if !ok {
+			book, err = h.service.GetBookByID(r.Context(), id)
+			if err != nil {
+				_ = render.Render(w, r, RepositoryErrorRenderer(err))
+				return
+			}
+			books[id] = book
+		}
+		code, err := bookBarcode(book)
+		if err != nil {
+			_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("book %d has no ISBN", book.ID)))
+			return
+		}
+		labels = append(labels, barcode.Label{
+			Title:    book.Name,
+			Subtitle: strconv.FormatFloat(book.Price, 'f', 2, 64),
+			Code:     code,
+		})
+	}
+	var sheet bytes.Buffer
+	if err := barcode.WriteLabelSheet(&sheet, labels); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	_, _ = sheet.WriteTo(w)
+}
+
+func parseLabelIDs(value string) ([]int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("ids is required")
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) > barcode.LabelsPerSheet {
+		return nil, fmt.Errorf("ids takes at most %d books", barcode.LabelsPerSheet)
+	}
+	ids := make([]int, len(parts))
+	for i, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("ids has to be a comma separated list of book IDs")
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// bookBarcode returns the EAN-13 an ISBN-13 is printed as.
+func bookBarcode(book models.Book) (barcode.EAN13, error) {
+	if book.ISBN == "" {
+		return barcode.EAN13{}, fmt.Errorf("book has no ISBN")
+	}
+	return barcode.NewEAN13(book.ISBN)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBarcodes(t *testing.T) {
+	book := models.Book{ID: 1, Name: "Pride & Prejudice", ISBN: "9780306406157", Genre: 2, Price: 9.5}
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		target               string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedBodyPrefix   string
+		expectedResponseBody string
+	}{
+		{
+			name:   "SVG",
+			target: "/books/1/barcode.svg",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(book, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/svg+xml",
+			expectedBodyPrefix:  `<svg xmlns="http://www.w3.org/2000/svg" width="339" height="228"`,
+		},
+		{
+			name:   "PNG",
+			target: "/books/1/barcode.png?scale=1",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(book, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/png",
+			expectedBodyPrefix:  "\x89PNG",
+		},
+		{
+			name:   "No ISBN",
+			target: "/books/1/barcode.png",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(models.Book{ID: 1, Name: "hello"}, nil)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"book has no ISBN\"}\n",
+		},
+		{
+			name:   "Book not found",
+			target: "/books/1/barcode.svg",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(models.Book{}, db.ErrNoMatch)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
+		},
+		{
+			name:                 "Invalid scale",
+			target:               "/books/1/barcode.svg?scale=0",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"scale has to be between 1 and 10\"}\n",
+		},
+		{
+			name:   "Label sheet",
+			target: "/books/labels.svg?ids=1,1",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(book, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/svg+xml",
+			expectedBodyPrefix: `<svg xmlns="http://www.w3.org/2000/svg" width="210mm" height="297mm" viewBox="0 0 210 297">` +
+				`<g transform="translate(0 0.5)" font-family="sans-serif"><text x="4" y="6" font-size="3.5">Pride &amp; Prejudice</text>` +
+				`<text x="4" y="10.5" font-size="3">9.50</text>`,
+		},
+		{
+			name:   "Label for a book without ISBN",
+			target: "/books/labels.svg?ids=2",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 2).Return(models.Book{ID: 2, Name: "hello"}, nil)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"book 2 has no ISBN\"}\n",
+		},
+		{
+			name:                 "Too many labels",
+			target:               "/books/labels.svg?ids=" + strings.Repeat("1,", 24) + "1",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"ids takes at most 24 books\"}\n",
+		},
+		{
+			name:                 "Invalid ids",
+			target:               "/books/labels.svg?ids=1,x",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"ids has to be a comma separated list of book IDs\"}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			handler := NewHandler(service.NewService(mockManager))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.target, nil)
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), test.expectedContentType)
+			if test.expectedBodyPrefix != "" {
+				assert.True(t, strings.HasPrefix(w.Body.String(), test.expectedBodyPrefix), w.Body.String())
+			} else {
+				assert.Equal(t, test.expectedResponseBody, w.Body.String())
+			}
+		})
+	}
+}
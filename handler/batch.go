@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+)
+
+// errBatchRolledBack is reported for the operations of an atomic batch that
+// didn't fail themselves but were undone because another one did.
+var errBatchRolledBack = fmt.Errorf("batch was rolled back")
+
+// BatchBooks runs a JSON array of create, update and delete operations. In
+// the default atomic mode either all of them are applied or none is, and the
+// response has the status of the operation that failed. With
+// mode=best_effort every valid operation is tried on its own and the
+// response is always 200. Either way the body holds a result per operation.
+func (h *Handler) BatchBooks(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = batchAtomic
+	}
+	if mode != batchAtomic && mode != batchBestEffort {
+		_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("mode has to be one of %s, %s", batchAtomic, batchBestEffort)))
+		return
+	}
+	atomic := mode == batchAtomic
+
+	var operations []models.BatchOperation
+	if err := render.DecodeJSON(r.Body, &operations); err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	if len(operations) == 0 || len(operations) > models.MaxBatchSize {
+		_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("batch has to have between 1 and %d operations", models.MaxBatchSize)))
+		return
+	}
+
+	results := make([]models.BatchResult, len(operations))
+	var valid []models.BatchOperation
+	var validIndexes []int
+	invalid := false
+	for i := range operations {
+		results[i] = models.BatchResult{Op: operations[i].Op, ID: operations[i].ID}
+		if err := h.bindBatchOperation(r, &operations[i]); err != nil {
+			results[i].Err = err
+			invalid = true
+			continue
+		}
+		valid = append(valid, operations[i])
+		validIndexes = append(validIndexes, i)
+	}
+
+	if len(valid) != 0 && !(atomic && invalid) {
+		ran, err := h.service.BatchBooks(r.Context(), valid, atomic)
+		if err != nil {
+			_ = render.Render(w, r, RepositoryErrorRenderer(err))
+			return
+		}
+		for j, i := range validIndexes {
+			results[i] = ran[j]
+		}
+	}
+
+	status := http.StatusOK
+	failed := false
+	for i := range results {
+		if results[i].Err == nil {
+			continue
+		}
+		response := RepositoryErrorRenderer(results[i].Err)
+		results[i].Status = response.StatusCode
+		results[i].Message = response.Message
+		results[i].Errors = response.FieldErrors
+		if atomic && !failed {
+			status = response.StatusCode
+		}
+		failed = true
+	}
+	for i := range results {
+		switch {
+		case results[i].Err != nil:
+		case atomic && failed:
+			results[i].Status = http.StatusFailedDependency
+			results[i].Message = errBatchRolledBack.Error()
+		case results[i].Op == models.BatchCreate:
+			results[i].Status = http.StatusCreated
+		case results[i].Op == models.BatchDelete:
+			results[i].Status = http.StatusNoContent
+		default:
+			results[i].Status = http.StatusOK
+		}
+	}
+
+	render.Status(r, status)
+	if err := render.Render(w, r, &models.BatchResponse{Results: results}); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+// bindBatchOperation validates an operation the way the single book
+// endpoints validate their requests.
+func (h *Handler) bindBatchOperation(r *http.Request, operation *models.BatchOperation) error {
+	if err := operation.Bind(r); err != nil {
+		return err
+	}
+	if h.requireIfMatch && operation.Op != models.BatchCreate && operation.Version == 0 {
+		return models.ValidationErrors{{Field: "version", Message: "version is a required field"}}
+	}
+	return nil
+}
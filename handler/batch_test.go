@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBatchBooks(t *testing.T) {
+	book := models.Book{Name: "Book1", Genre: 1, Price: 2.5, Amount: 4}
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		target               string
+		inputBody            string
+		options              []Option
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "Atomic Ok",
+			target: "/books:batch",
+			inputBody: `[{"op":"create","book":{"name":"Book1","genre":1,"price":2.5,"amount":4}},` +
+				`{"op":"update","id":2,"version":3,"book":{"name":"Book1","genre":1,"price":2.5,"amount":4}},` +
+				`{"op":"delete","id":3}]`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(&models.GenreList{Genres: []models.Genre{{ID: 1, Name: "adventure"}}}, nil)
+				r.EXPECT().BatchBooks(gomock.Any(), []models.BatchOperation{
+					{Op: models.BatchCreate, Book: &book},
+					{Op: models.BatchUpdate, ID: 2, Version: 3, Book: &book},
+					{Op: models.BatchDelete, ID: 3},
+				}, true).Return([]models.BatchResult{
+					{Op: models.BatchCreate, ID: 7},
+					{Op: models.BatchUpdate, ID: 2},
+					{Op: models.BatchDelete, ID: 3},
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"results\":[{\"op\":\"create\",\"id\":7,\"status\":201}," +
+				"{\"op\":\"update\",\"id\":2,\"status\":200},{\"op\":\"delete\",\"id\":3,\"status\":204}]}\n",
+		},
+		{
+			name:   "Atomic rolled back",
+			target: "/books:batch",
+			inputBody: `[{"op":"create","book":{"name":"Book1","genre":1,"price":2.5,"amount":4}},` +
+				`{"op":"delete","id":3},{"op":"delete","id":4}]`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(&models.GenreList{Genres: []models.Genre{{ID: 1, Name: "adventure"}}}, nil)
+				r.EXPECT().BatchBooks(gomock.Any(), gomock.Any(), true).Return([]models.BatchResult{
+					{Op: models.BatchCreate},
+					{Op: models.BatchDelete, ID: 3, Err: db.ErrNoMatch},
+					{Op: models.BatchDelete, ID: 4},
+				}, nil)
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponseBody: "{\"results\":[{\"op\":\"create\",\"status\":424,\"message\":\"batch was rolled back\"}," +
+				"{\"op\":\"delete\",\"id\":3,\"status\":404,\"message\":\"no matching record\"}," +
+				"{\"op\":\"delete\",\"id\":4,\"status\":424,\"message\":\"batch was rolled back\"}]}\n",
+		},
+		{
+			name:               "Atomic with invalid operation",
+			target:             "/books:batch",
+			inputBody:          `[{"op":"delete","id":3},{"op":"create","book":{"genre":1,"price":-1}}]`,
+			mockBehavior:       func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: "{\"results\":[{\"op\":\"delete\",\"id\":3,\"status\":424,\"message\":\"batch was rolled back\"}," +
+				"{\"op\":\"create\",\"status\":400,\"message\":\"name is a required field; price can't be less than 0\"," +
+				"\"errors\":[{\"field\":\"name\",\"message\":\"name is a required field\"}," +
+				"{\"field\":\"price\",\"message\":\"price can't be less than 0\"}]}]}\n",
+		},
+		{
+			name:      "Best effort",
+			target:    "/books:batch?mode=best_effort",
+			inputBody: `[{"op":"delete"},{"op":"delete","id":3},{"op":"delete","id":4},{"op":"rename","id":5}]`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().BatchBooks(gomock.Any(), []models.BatchOperation{
+					{Op: models.BatchDelete, ID: 3},
+					{Op: models.BatchDelete, ID: 4},
+				}, false).Return([]models.BatchResult{
+					{Op: models.BatchDelete, ID: 3, Err: db.ErrVersionMismatch},
+					{Op: models.BatchDelete, ID: 4},
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"results\":[{\"op\":\"delete\",\"status\":400,\"message\":\"id is a required field\"," +
+				"\"errors\":[{\"field\":\"id\",\"message\":\"id is a required field\"}]}," +
+				"{\"op\":\"delete\",\"id\":3,\"status\":412,\"message\":\"book was modified since it was read\"}," +
+				"{\"op\":\"delete\",\"id\":4,\"status\":204}," +
+				"{\"op\":\"rename\",\"id\":5,\"status\":400,\"message\":\"op has to be one of create, update, delete\"," +
+				"\"errors\":[{\"field\":\"op\",\"message\":\"op has to be one of create, update, delete\"}]}]}\n",
+		},
+		{
+			name:               "Version required",
+			target:             "/books:batch",
+			inputBody:          `[{"op":"delete","id":3}]`,
+			options:            []Option{WithRequiredIfMatch()},
+			mockBehavior:       func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: "{\"results\":[{\"op\":\"delete\",\"id\":3,\"status\":400,\"message\":\"version is a required field\"," +
+				"\"errors\":[{\"field\":\"version\",\"message\":\"version is a required field\"}]}]}\n",
+		},
+		{
+			name:                 "Invalid mode",
+			target:               "/books:batch?mode=eventually",
+			inputBody:            `[{"op":"delete","id":3}]`,
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"mode has to be one of atomic, best_effort\"}\n",
+		},
+		{
+			name:                 "Empty batch",
+			target:               "/books:batch",
+			inputBody:            `[]`,
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"batch has to have between 1 and 1000 operations\"}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			services := service.NewService(mockManager)
+			handler := NewHandler(services, test.options...)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", test.target, bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+// TestMissingGenreErrorShape checks that a book with a missing genre is
+// rejected the same way alone and inside a batch.
+func TestMissingGenreErrorShape(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	mockManager := mock.NewMockDatabaseBooksManager(c)
+	mockManager.EXPECT().GetGenreByID(gomock.Any(), 9).Return(models.Genre{}, db.ErrNoMatch)
+	mockManager.EXPECT().GetAllGenres(gomock.Any()).Return(&models.GenreList{Genres: []models.Genre{{ID: 1, Name: "adventure"}}}, nil)
+	router := NewHandler(service.NewService(mockManager)).InitRoutes()
+
+	post := func(target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", target, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		// Problem details carry the field errors of a single request.
+		req.Header.Set("Accept", ContentTypeProblemJSON)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	book := `{"name":"Book1","genre":9,"price":2.5,"amount":4}`
+	single := post("/books", book)
+	batch := post("/books:batch", `[{"op":"create","book":`+book+`}]`)
+
+	var singleResponse Problem
+	var batchResponse models.BatchResponse
+	assert.NoError(t, json.Unmarshal(single.Body.Bytes(), &singleResponse))
+	assert.NoError(t, json.Unmarshal(batch.Body.Bytes(), &batchResponse))
+	assert.Equal(t, http.StatusBadRequest, single.Code)
+	assert.Equal(t, single.Code, batch.Code)
+	if assert.Len(t, batchResponse.Results, 1) {
+		result := batchResponse.Results[0]
+		assert.Equal(t, single.Code, result.Status)
+		assert.Equal(t, singleResponse.Detail, result.Message)
+		assert.Equal(t, singleResponse.Errors, result.Errors)
+		assert.Equal(t, []models.FieldError{{Field: "genre", Message: "genre doesn't exist"}}, result.Errors)
+	}
+}
@@ -1,15 +1,80 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
 	"github.com/go-chi/render"
+	"log"
+	"mime"
 	"net/http"
+	"runtime/debug"
+	"strings"
 )
 
+const ContentTypeProblemJSON = "application/problem+json"
+
 type ErrorResponse struct {
-	Err        error  `json:"-"`
-	StatusCode int    `json:"-"`
-	StatusText string `json:"status_text"`
-	Message    string `json:"message"`
+	Err         error               `json:"-"`
+	StatusCode  int                 `json:"-"`
+	StatusText  string              `json:"status_text"`
+	Message     string              `json:"message"`
+	FieldErrors []models.FieldError `json:"-"`
+}
+
+// Problem is the RFC 7807 representation of an ErrorResponse, sent to
+// clients that ask for application/problem+json.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Errors   []models.FieldError `json:"errors,omitempty"`
+}
+
+func init() {
+	render.Respond = respond
+}
+
+// respond replaces render's default responder so that error responses can
+// be negotiated into problem details. Everything else is left to render.
+func respond(w http.ResponseWriter, r *http.Request, v interface{}) {
+	if e, ok := v.(*ErrorResponse); ok && acceptsProblemJSON(r) {
+		writeProblem(w, r, e)
+		return
+	}
+	render.DefaultResponder(w, r, v)
+}
+
+func acceptsProblemJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && mediaType == ContentTypeProblemJSON {
+			return true
+		}
+	}
+	return false
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, e *ErrorResponse) {
+	title := e.StatusText
+	if title == "" {
+		title = http.StatusText(e.StatusCode)
+	}
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   e.StatusCode,
+		Detail:   e.Message,
+		Instance: r.URL.RequestURI(),
+		Errors:   e.FieldErrors,
+	}
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.WriteHeader(e.StatusCode)
+	_ = json.NewEncoder(w).Encode(problem)
 }
 
 var (
@@ -24,12 +89,17 @@ func (e *ErrorResponse) Render(w http.ResponseWriter, r *http.Request) error {
 }
 
 func ErrorRenderer(err error) *ErrorResponse {
-	return &ErrorResponse{
+	response := &ErrorResponse{
 		Err:        err,
 		StatusCode: 400,
 		StatusText: "Bad request",
 		Message:    err.Error(),
 	}
+	var fieldErrors models.ValidationErrors
+	if errors.As(err, &fieldErrors) {
+		response.FieldErrors = fieldErrors
+	}
+	return response
 }
 
 func ServerErrorRenderer(err error) *ErrorResponse {
@@ -40,3 +110,143 @@ func ServerErrorRenderer(err error) *ErrorResponse {
 		Message:    err.Error(),
 	}
 }
+
+func NotFoundErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 404,
+		StatusText: "Not found",
+		Message:    err.Error(),
+	}
+}
+
+func ConflictErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 409,
+		StatusText: "Conflict",
+		Message:    err.Error(),
+	}
+}
+
+func PreconditionFailedErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 412,
+		StatusText: "Precondition failed",
+		Message:    err.Error(),
+	}
+}
+
+func RequestEntityTooLargeErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 413,
+		StatusText: "Request entity too large",
+		Message:    err.Error(),
+	}
+}
+
+func UnsupportedMediaTypeErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 415,
+		StatusText: "Unsupported media type",
+		Message:    err.Error(),
+	}
+}
+
+func ServiceUnavailableErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 503,
+		StatusText: "Service unavailable",
+		Message:    err.Error(),
+	}
+}
+
+// ClientClosedRequestErrorRenderer answers a client that went away before
+// its request was done, with the non-standard 499 nginx logs it as. Nobody
+// reads the response, but it keeps access logs from blaming the server.
+func ClientClosedRequestErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 499,
+		StatusText: "Client closed request",
+		Message:    err.Error(),
+	}
+}
+
+func PreconditionRequiredErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 428,
+		StatusText: "Precondition required",
+		Message:    err.Error(),
+	}
+}
+
+func UnprocessableEntityErrorRenderer(err error) *ErrorResponse {
+	return &ErrorResponse{
+		Err:        err,
+		StatusCode: 422,
+		StatusText: "Unprocessable entity",
+		Message:    err.Error(),
+	}
+}
+
+var errInternal = errors.New("internal error")
+
+// recoverPanics answers a request whose handler panicked with a 500 and logs
+// the panic with its stack, instead of letting net/http drop the connection.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			log.Printf("panic serving %s %s: %v\n%s", r.Method, r.URL.Path, recovered, debug.Stack())
+			_ = render.Render(w, r, ServerErrorRenderer(errInternal))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RepositoryErrorRenderer picks the response for an error coming from the
+// service layer. Field errors are reported like a failed Bind, timed out
+// queries as 503, queries cancelled by the client going away as 499, and
+// errors outside the db package's types are treated as internal server
+// errors.
+func RepositoryErrorRenderer(err error) *ErrorResponse {
+	var (
+		fieldErrors        models.ValidationErrors
+		notFound           *db.NotFoundError
+		conflict           *db.ConflictError
+		validation         *db.ValidationError
+		preconditionFailed *db.PreconditionFailedError
+	)
+	switch {
+	case errors.Is(err, errPreconditionRequired):
+		return PreconditionRequiredErrorRenderer(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return ServiceUnavailableErrorRenderer(err)
+	case errors.Is(err, context.Canceled):
+		return ClientClosedRequestErrorRenderer(err)
+	case errors.As(err, &fieldErrors):
+		return ErrorRenderer(err)
+	case errors.As(err, &notFound):
+		return NotFoundErrorRenderer(err)
+	case errors.As(err, &conflict):
+		return ConflictErrorRenderer(err)
+	case errors.As(err, &validation):
+		return UnprocessableEntityErrorRenderer(err)
+	case errors.As(err, &preconditionFailed):
+		return PreconditionFailedErrorRenderer(err)
+	default:
+		return ServerErrorRenderer(err)
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/go-chi/render"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemJSON(t *testing.T) {
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		accept               string
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Default shape",
+			method:               "POST",
+			target:               "/books/",
+			inputBody:            `{"price": 67.88, "genre": 1, "amount": 5}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json; charset=utf-8",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"name is a required field\"}\n",
+		},
+		{
+			name:                "Validation problem",
+			method:              "POST",
+			target:              "/books/",
+			inputBody:           `{"price": 67.88, "genre": 1, "amount": 5}`,
+			accept:              "application/problem+json",
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: ContentTypeProblemJSON,
+			expectedResponseBody: "{\"type\":\"about:blank\",\"title\":\"Bad request\",\"status\":400," +
+				"\"detail\":\"name is a required field\",\"instance\":\"/books/\"," +
+				"\"errors\":[{\"field\":\"name\",\"message\":\"name is a required field\"}]}\n",
+		},
+		{
+			name:                "Not found problem",
+			method:              "GET",
+			target:              "/unknown?x=1",
+			accept:              "application/json;q=0.9, application/problem+json",
+			expectedStatusCode:  http.StatusNotFound,
+			expectedContentType: ContentTypeProblemJSON,
+			expectedResponseBody: "{\"type\":\"about:blank\",\"title\":\"Not found\",\"status\":404," +
+				"\"detail\":\"resource not found\",\"instance\":\"/unknown?x=1\"}\n",
+		},
+		{
+			name:                "Method not allowed problem",
+			method:              "PATCH",
+			target:              "/books/",
+			accept:              "application/problem+json",
+			expectedStatusCode:  http.StatusMethodNotAllowed,
+			expectedContentType: ContentTypeProblemJSON,
+			expectedResponseBody: "{\"type\":\"about:blank\",\"title\":\"Method Not Allowed\",\"status\":405," +
+				"\"detail\":\"Method not allowed\",\"instance\":\"/books/\"}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			services := service.NewService(mockManager)
+			handler := NewHandler(services)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	// A misspelled binding rule is a bug, answered as one.
+	handler := recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input := struct {
+			Name string `json:"name" binding:"requird"`
+		}{}
+		if err := models.Validate(&input); err != nil {
+			_ = render.Render(w, r, ErrorRenderer(err))
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/books/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "{\"status_text\":\"Internal server error\",\"message\":\"internal error\"}\n", w.Body.String())
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+)
+
+var errPreconditionRequired = fmt.Errorf("If-Match header is required")
+
+func bookETag(version int) string {
+	return fmt.Sprintf("%q", strconv.Itoa(version))
+}
+
+// setWrittenETag tags the response to a successful write that was
+// conditional on version. Every write bumps the version by one, so the new
+// one is known without reading the book again. Unconditional writes go
+// untagged, since the version they overwrote isn't known.
+func setWrittenETag(w http.ResponseWriter, version int) {
+	if version > 0 {
+		w.Header().Set("ETag", bookETag(version+1))
+	}
+}
+
+// parseETags returns the versions listed in an If-Match or If-None-Match
+// header. Weak tags and tags that aren't ours are skipped, since they can
+// never match a book.
+func parseETags(header string) (versions []int, any bool) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err == nil && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+	return versions, false
+}
+
+func matchesETag(header string, version int) bool {
+	versions, any := parseETags(header)
+	if any {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedVersion turns the If-Match header of a write into the version the
+// repository has to find for the write to go through. Zero means the write
+// is unconditional.
+func (h *Handler) expectedVersion(r *http.Request, bookID int) (int, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if h.requireIfMatch {
+			return 0, errPreconditionRequired
+		}
+		return 0, nil
+	}
+	versions, any := parseETags(header)
+	switch {
+	case any:
+		return 0, nil
+	case len(versions) == 0:
+		return 0, db.ErrVersionMismatch
+	case len(versions) == 1:
+		return versions[0], nil
+	}
+	// With several candidates, pick the one the book is at now. The write
+	// is still conditional on it, so a concurrent change is caught.
+	book, err := h.service.GetBookByID(r.Context(), bookID)
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version == book.Version {
+			return version, nil
+		}
+	}
+	return 0, db.ErrVersionMismatch
+}
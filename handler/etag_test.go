@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	stored := models.Book{ID: 1, Name: "Book1", Genre: 1, Price: 2.5, Amount: 4, Available: true, Version: 3}
+	updated := models.Book{Name: "Book1", Genre: 1, Price: 2.5, Amount: 5}
+
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		method               string
+		inputBody            string
+		headers              map[string]string
+		options              []Option
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedETag         string
+		expectedResponseBody string
+	}{
+		{
+			name:   "Get sets ETag",
+			method: "GET",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(stored, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedETag:         `"3"`,
+			expectedResponseBody: "{\"id\":1,\"name\":\"Book1\",\"genre\":1,\"price\":2.5,\"amount\":4,\"available\":true,\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"}\n",
+		},
+		{
+			name:    "Get not modified",
+			method:  "GET",
+			headers: map[string]string{"If-None-Match": `"2", "3"`},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(stored, nil)
+			},
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       `"3"`,
+		},
+		{
+			name:    "Get modified",
+			method:  "GET",
+			headers: map[string]string{"If-None-Match": `"2"`},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(stored, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedETag:         `"3"`,
+			expectedResponseBody: "{\"id\":1,\"name\":\"Book1\",\"genre\":1,\"price\":2.5,\"amount\":4,\"available\":true,\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"}\n",
+		},
+		{
+			name:      "Put with matching If-Match",
+			method:    "PUT",
+			inputBody: `{"name": "Book1", "genre": 1, "price": 2.5, "amount": 5}`,
+			headers:   map[string]string{"If-Match": `"3"`},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				book := updated
+				book.Version = 3
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), 1, book).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedETag:         `"4"`,
+			expectedResponseBody: "{\"id\":1}\n",
+		},
+		{
+			name:      "Put with stale If-Match",
+			method:    "PUT",
+			inputBody: `{"name": "Book1", "genre": 1, "price": 2.5, "amount": 5}`,
+			headers:   map[string]string{"If-Match": `"2"`},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				book := updated
+				book.Version = 2
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), 1, book).Return(0, db.ErrVersionMismatch)
+			},
+			expectedStatusCode:   http.StatusPreconditionFailed,
+			expectedResponseBody: "{\"status_text\":\"Precondition failed\",\"message\":\"book was modified since it was read\"}\n",
+		},
+		{
+			name:      "Put with several If-Match tags",
+			method:    "PUT",
+			inputBody: `{"name": "Book1", "genre": 1, "price": 2.5, "amount": 5}`,
+			headers:   map[string]string{"If-Match": `"1", "3"`},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				book := updated
+				book.Version = 3
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(stored, nil)
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), 1, book).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedETag:         `"4"`,
+			expectedResponseBody: "{\"id\":1}\n",
+		},
+		{
+			name:                 "Put without required If-Match",
+			method:               "PUT",
+			inputBody:            `{"name": "Book1", "genre": 1, "price": 2.5, "amount": 5}`,
+			options:              []Option{WithRequiredIfMatch()},
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusPreconditionRequired,
+			expectedResponseBody: "{\"status_text\":\"Precondition required\",\"message\":\"If-Match header is required\"}\n",
+		},
+		{
+			name:      "Put without If-Match",
+			method:    "PUT",
+			inputBody: `{"name": "Book1", "genre": 1, "price": 2.5, "amount": 5}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), 1, updated).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":1}\n",
+		},
+		{
+			name:      "Patch sets ETag",
+			method:    "PATCH",
+			inputBody: `{"amount": 5}`,
+			headers:   map[string]string{"If-Match": `"3"`, "Content-Type": "application/merge-patch+json"},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				book := updated
+				book.ID, book.Available, book.Version = 1, true, 3
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(stored, nil)
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), 1, book).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedETag:         `"4"`,
+			expectedResponseBody: "{\"id\":1}\n",
+		},
+		{
+			name:      "Patch with stale If-Match",
+			method:    "PATCH",
+			inputBody: `{"amount": 5}`,
+			headers:   map[string]string{"If-Match": `"2"`, "Content-Type": "application/merge-patch+json"},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByID(gomock.Any(), 1).Return(stored, nil)
+			},
+			expectedStatusCode:   http.StatusPreconditionFailed,
+			expectedResponseBody: "{\"status_text\":\"Precondition failed\",\"message\":\"book was modified since it was read\"}\n",
+		},
+		{
+			name:    "Delete with If-Match",
+			method:  "DELETE",
+			headers: map[string]string{"If-Match": `"3"`},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().DeleteBookByID(gomock.Any(), 1, 3).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:    "Delete with any If-Match",
+			method:  "DELETE",
+			headers: map[string]string{"If-Match": `*`},
+			options: []Option{WithRequiredIfMatch()},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().DeleteBookByID(gomock.Any(), 1, 0).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			services := service.NewService(mockManager)
+			handler := NewHandler(services, test.options...)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, "/books/1", bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedETag, w.Header().Get("ETag"))
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
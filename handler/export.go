@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/catalog"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/go-chi/render"
+	"log"
+	"net/http"
+)
+
+// ExportBooks streams the books matching the GetAllBooks filters as a CSV,
+// NDJSON or TSV catalog, CSV by default. Rows are written as they are read,
+// so the whole catalog is never held in memory.
+func (h *Handler) ExportBooks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = catalog.FormatCSV
+	}
+	switch format {
+	case catalog.FormatCSV, catalog.FormatNDJSON, catalog.FormatTSV:
+	default:
+		_ = render.Render(w, r, ErrorRenderer(models.ValidationErrors{{Field: "format", Message: fmt.Sprintf(
+			"format has to be one of %s, %s, %s", catalog.FormatCSV, catalog.FormatNDJSON, catalog.FormatTSV)}}))
+		return
+	}
+	query.Del("format")
+	filter, err := models.ParseBookFilter(query)
+	if err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+
+	// The writer is made on the first row, so that an error before it can
+	// still get a proper error response.
+	var writer catalog.Writer
+	start := func() error {
+		w.Header().Set("Content-Type", catalog.ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="books.%s"`, format))
+		writer, err = catalog.NewWriter(w, format)
+		return err
+	}
+	err = h.service.ExportBooks(r.Context(), filter, func(book models.Book, genre string) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return writer.Write(book, genre)
+	})
+	if err != nil && writer == nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err != nil {
+		// The status is already sent, so the only way to tell the client
+		// the catalog is incomplete is to break the connection.
+		log.Printf("export failed: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+	if writer == nil {
+		if err := start(); err != nil {
+			log.Printf("export failed: %v", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		log.Printf("export failed: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
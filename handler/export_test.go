@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExportBooks(t *testing.T) {
+	exportBooks := func(books ...models.Book) func(interface{}, models.BookFilter, func(models.Book, string) error) error {
+		return func(_ interface{}, _ models.BookFilter, fn func(models.Book, string) error) error {
+			for _, book := range books {
+				if err := fn(book, "fantasy"); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		target               string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedResponseBody string
+	}{
+		{
+			name:   "CSV by default",
+			target: "/books/export?price[gte]=5",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				filter := models.BookFilter{Conditions: []models.Condition{{Field: "price", Op: models.OpGte, Values: []interface{}{5.0}}}}
+				r.EXPECT().ExportBooks(gomock.Any(), filter, gomock.Any()).
+					DoAndReturn(exportBooks(models.Book{ID: 1, Name: "The Hobbit", Genre: 3, Price: 9.5, Amount: 3}))
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedContentType:  "text/csv",
+			expectedResponseBody: "id,name,genre,price,amount,isbn\n1,The Hobbit,fantasy,9.5,3,\n",
+		},
+		{
+			name:   "NDJSON",
+			target: "/books/export?format=ndjson&availability=all",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().ExportBooks(gomock.Any(), models.BookFilter{Availability: models.AvailabilityAll}, gomock.Any()).
+					DoAndReturn(exportBooks(models.Book{ID: 1, Name: "Emma", Genre: 3, Price: 4}))
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedContentType:  "application/x-ndjson",
+			expectedResponseBody: `{"id":1,"name":"Emma","genre":"fantasy","price":4,"amount":0}` + "\n",
+		},
+		{
+			name:   "Empty TSV",
+			target: "/books/export?format=tsv",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().ExportBooks(gomock.Any(), models.BookFilter{}, gomock.Any()).DoAndReturn(exportBooks())
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedContentType:  "text/tab-separated-values",
+			expectedResponseBody: "id\tname\tgenre\tprice\tamount\tisbn\n",
+		},
+		{
+			name:                 "Unknown format",
+			target:               "/books/export?format=xlsx",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"format has to be one of csv, ndjson, tsv\"}\n",
+		},
+		{
+			name:                 "Invalid filter",
+			target:               "/books/export?title=x",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"title isn't a known filter\"}\n",
+		},
+		{
+			name:   "Error before the first row",
+			target: "/books/export",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().ExportBooks(gomock.Any(), models.BookFilter{}, gomock.Any()).Return(errors.New("connection refused"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Internal server error\",\"message\":\"connection refused\"}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			services := service.NewService(mockManager)
+			handler := NewHandler(services)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.target, nil)
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), test.expectedContentType)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestExportBooksAbortsMidStream(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockManager := mock.NewMockDatabaseBooksManager(c)
+	mockManager.EXPECT().ExportBooks(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, _ models.BookFilter, fn func(models.Book, string) error) error {
+			if err := fn(models.Book{ID: 1, Name: "Emma"}, "classics"); err != nil {
+				return err
+			}
+			return errors.New("connection reset")
+		})
+	handler := NewHandler(service.NewService(mockManager))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/books/export", nil)
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.InitRoutes().ServeHTTP(w, req)
+	})
+}
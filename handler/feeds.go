@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/opds"
+	"github.com/GlobantObrikosina/golang-rest-api/rss"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// newBooksFeedSize is how many of the latest books the new arrivals feeds
+// list.
+const newBooksFeedSize = 50
+
+func (h *Handler) feeds(router chi.Router) {
+	router.Get("/new.atom", h.GetNewBooksAtom)
+	router.Get("/new.rss", h.GetNewBooksRSS)
+}
+
+// newBooks is a page of the most recently added books, optionally limited
+// to the genres of the genre parameter.
+type newBooks struct {
+	title      string
+	books      []models.Book
+	genreNames map[int]string
+}
+
+func (h *Handler) newBooks(r *http.Request) (newBooks, error) {
+	filter := models.BookFilter{Availability: models.AvailabilityAll}
+	if genre, ok := r.URL.Query()["genre"]; ok {
+		parsed, err := models.ParseBookFilter(url.Values{"genre": genre})
+		if err != nil {
+			return newBooks{}, err
+		}
+		filter.Conditions = parsed.Conditions
+	}
+	genres, err := h.service.GetAllGenres(r.Context())
+	if err != nil {
+		return newBooks{}, err
+	}
+	result := newBooks{title: "New arrivals", genreNames: make(map[int]string, len(genres.Genres))}
+	for _, genre := range genres.Genres {
+		result.genreNames[genre.ID] = genre.Name
+	}
+	var names []string
+	for _, condition := range filter.Conditions {
+		for _, id := range condition.Values {
+			name, ok := result.genreNames[id.(int)]
+			if !ok {
+				return newBooks{}, models.ValidationErrors{{Field: "genre", Message: "genre doesn't exist"}}
+			}
+			names = append(names, name)
+		}
+	}
+	if len(names) != 0 {
+		result.title += " in " + strings.Join(names, ", ")
+	}
+
+	page := models.Page{Limit: newBooksFeedSize, Sort: models.Sort{{Field: "created_at", Desc: true}}}
+	list, err := h.service.GetAllBooks(r.Context(), filter, page)
+	if err != nil {
+		return newBooks{}, err
+	}
+	result.books = list.Books
+	return result, nil
+}
+
+// updated is when the newest change to any of the books happened, or now if
+// there are none.
+func (n newBooks) updated(now func() time.Time) time.Time {
+	var updated time.Time
+	for _, book := range n.books {
+		if book.UpdatedAt.After(updated) {
+			updated = book.UpdatedAt
+		}
+	}
+	if updated.IsZero() {
+		return now().UTC()
+	}
+	return updated.UTC()
+}
+
+// GetNewBooksAtom is an Atom feed of the latest books.
+func (h *Handler) GetNewBooksAtom(w http.ResponseWriter, r *http.Request) {
+	result, err := h.newBooks(r)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	feed := opds.Feed{
+		ID:      opdsID + "new",
+		Title:   result.title,
+		Updated: result.updated(h.now),
+		Author:  &opds.Person{Name: "Bookstore"},
+		Links:   []opds.Link{{Rel: opds.RelSelf, Href: r.URL.RequestURI(), Type: opds.AtomType}},
+	}
+	for _, book := range result.books {
+		entry := bookEntry(book, result.genreNames[book.Genre])
+		published := book.CreatedAt.UTC()
+		entry.Published = &published
+		entry.Links = []opds.Link{{Rel: opds.RelAlternate, Href: bookPath(book.ID), Type: "application/json"}}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	writeXML(w, r, opds.AtomType, feed)
+}
+
+// GetNewBooksRSS is an RSS feed of the latest books. RSS wants absolute
+// links, so they are made from the request's host.
+func (h *Handler) GetNewBooksRSS(w http.ResponseWriter, r *http.Request) {
+	result, err := h.newBooks(r)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	channel := rss.Channel{
+		Title:         result.title,
+		Link:          absoluteURL(r, "/books"),
+		Description:   "Books recently added to the catalog",
+		LastBuildDate: rss.Date(result.updated(h.now)),
+	}
+	for _, book := range result.books {
+		channel.Items = append(channel.Items, rss.Item{
+			Title:       book.Name,
+			Link:        absoluteURL(r, bookPath(book.ID)),
+			Description: bookSummary(book),
+			Category:    result.genreNames[book.Genre],
+			GUID:        rss.GUID{Value: opdsID + "books:" + strconv.Itoa(book.ID)},
+			PubDate:     rss.Date(book.CreatedAt.UTC()),
+		})
+	}
+	writeXML(w, r, rss.ContentType, rss.New(channel))
+}
+
+func bookPath(id int) string {
+	return "/books/" + strconv.Itoa(id)
+}
+
+func bookSummary(book models.Book) string {
+	return fmt.Sprintf("Price: %s. In stock: %d.", strconv.FormatFloat(book.Price, 'f', -1, 64), book.Amount)
+}
+
+func absoluteURL(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + path
+}
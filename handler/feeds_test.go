@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewBooksFeeds(t *testing.T) {
+	clock := WithClock(func() time.Time { return time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC) })
+
+	genres := &models.GenreList{Genres: []models.Genre{{ID: 1, Name: "adventure"}, {ID: 3, Name: "Fantasy"}}}
+	newest := models.Page{Limit: newBooksFeedSize, Sort: models.Sort{{Field: "created_at", Desc: true}}}
+	books := &models.BookList{Books: []models.Book{{
+		ID: 5, Name: "The Hobbit", Genre: 3, Price: 9.5, Amount: 3,
+		CreatedAt: time.Date(2021, 2, 1, 9, 30, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2021, 2, 2, 10, 0, 0, 0, time.UTC),
+	}}}
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		target               string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedResponseBody string
+	}{
+		{
+			name:   "Atom",
+			target: "/feeds/new.atom?genre=3",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+				r.EXPECT().GetAllBooks(gomock.Any(), models.BookFilter{
+					Conditions:   []models.Condition{{Field: "genre", Op: models.OpEq, Values: []interface{}{3}}},
+					Availability: models.AvailabilityAll,
+				}, newest).Return(books, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/atom+xml",
+			expectedResponseBody: `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>urn:bookstore:new</id>
+  <title>New arrivals in Fantasy</title>
+  <updated>2021-02-02T10:00:00Z</updated>
+  <author>
+    <name>Bookstore</name>
+  </author>
+  <link rel="self" href="/feeds/new.atom?genre=3" type="application/atom+xml"></link>
+  <entry>
+    <id>urn:bookstore:books:5</id>
+    <title>The Hobbit</title>
+    <updated>2021-02-02T10:00:00Z</updated>
+    <published>2021-02-01T09:30:00Z</published>
+    <category term="3" label="Fantasy"></category>
+    <content type="text">Price: 9.5. In stock: 3.</content>
+    <link rel="alternate" href="/books/5" type="application/json"></link>
+  </entry>
+</feed>`,
+		},
+		{
+			name:   "RSS",
+			target: "/feeds/new.rss",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+				r.EXPECT().GetAllBooks(gomock.Any(), models.BookFilter{Availability: models.AvailabilityAll}, newest).
+					Return(books, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/rss+xml",
+			expectedResponseBody: `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>New arrivals</title>
+    <link>http://example.com/books</link>
+    <description>Books recently added to the catalog</description>
+    <lastBuildDate>Tue, 02 Feb 2021 10:00:00 +0000</lastBuildDate>
+    <item>
+      <title>The Hobbit</title>
+      <link>http://example.com/books/5</link>
+      <description>Price: 9.5. In stock: 3.</description>
+      <category>Fantasy</category>
+      <guid isPermaLink="false">urn:bookstore:books:5</guid>
+      <pubDate>Mon, 01 Feb 2021 09:30:00 +0000</pubDate>
+    </item>
+  </channel>
+</rss>`,
+		},
+		{
+			name:   "Empty RSS",
+			target: "/feeds/new.rss",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+				r.EXPECT().GetAllBooks(gomock.Any(), gomock.Any(), newest).Return(&models.BookList{}, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/rss+xml",
+			expectedResponseBody: `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>New arrivals</title>
+    <link>http://example.com/books</link>
+    <description>Books recently added to the catalog</description>
+    <lastBuildDate>Mon, 01 Mar 2021 12:00:00 +0000</lastBuildDate>
+  </channel>
+</rss>`,
+		},
+		{
+			name:   "Unknown genre",
+			target: "/feeds/new.atom?genre=9",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"genre doesn't exist\"}\n",
+		},
+		{
+			name:                 "Invalid genre",
+			target:               "/feeds/new.rss?genre=fantasy",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"genre has to be a positive integer\"}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			services := service.NewService(mockManager)
+			handler := NewHandler(services, clock)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.target, nil)
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), test.expectedContentType)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"net/http"
+)
+
+// sparseBook is a book encoded with only the fields a client asked for.
+type sparseBook struct {
+	json.RawMessage
+}
+
+func (sparseBook) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// sparseBookList is a models.BookList made of sparse books.
+type sparseBookList struct {
+	Books      []sparseBook `json:"books"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	HasMore    bool         `json:"has_more"`
+}
+
+func (*sparseBookList) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func selectBookFields(book models.Book, fields models.Fields) (sparseBook, error) {
+	data, err := fields.Select(book)
+	return sparseBook{data}, err
+}
+
+func selectBookListFields(list *models.BookList, fields models.Fields) (*sparseBookList, error) {
+	sparse := &sparseBookList{NextCursor: list.NextCursor, HasMore: list.HasMore}
+	for _, book := range list.Books {
+		selected, err := selectBookFields(book, fields)
+		if err != nil {
+			return nil, err
+		}
+		sparse.Books = append(sparse.Books, selected)
+	}
+	return sparse, nil
+}
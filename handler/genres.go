@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"net/http"
+	"strconv"
+)
+
+var genreIDKey = "genreID"
+
+func (h *Handler) genres(router chi.Router) {
+	router.Get("/", h.GetAllGenres)
+	router.Post("/", h.CreateGenre)
+	router.Route("/{genreID}", func(router chi.Router) {
+		router.Use(h.GenreContext)
+		router.Get("/", h.GetGenre)
+		router.Put("/", h.UpdateGenre)
+		router.Delete("/", h.DeleteGenreByID)
+	})
+}
+
+func (h *Handler) GenreContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		genreID := chi.URLParam(r, "genreID")
+
+		id, err := strconv.Atoi(genreID)
+		if err != nil {
+			_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("invalid genre ID")))
+			return
+		}
+		ctx := context.WithValue(r.Context(), genreIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *Handler) GetAllGenres(w http.ResponseWriter, r *http.Request) {
+	genres, err := h.service.GetAllGenres(r.Context())
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, genres); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+func (h *Handler) CreateGenre(w http.ResponseWriter, r *http.Request) {
+	genre := &models.Genre{}
+	if err := render.Bind(r, genre); err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	id, err := h.service.CreateGenre(r.Context(), genre)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, models.CreateGenreResponse{GenreID: id}); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+func (h *Handler) GetGenre(w http.ResponseWriter, r *http.Request) {
+	genreID := r.Context().Value(genreIDKey).(int)
+	genre, err := h.service.GetGenreByID(r.Context(), genreID)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, &genre); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+func (h *Handler) UpdateGenre(w http.ResponseWriter, r *http.Request) {
+	genreID := r.Context().Value(genreIDKey).(int)
+	genreData := models.Genre{}
+	if err := render.Bind(r, &genreData); err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	id, err := h.service.UpdateGenreByID(r.Context(), genreID, genreData)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, models.CreateGenreResponse{GenreID: id}); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
+
+func (h *Handler) DeleteGenreByID(w http.ResponseWriter, r *http.Request) {
+	genreID := r.Context().Value(genreIDKey).(int)
+	if err := h.service.DeleteGenreByID(r.Context(), genreID); err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	render.NoContent(w, r)
+}
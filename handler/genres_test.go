@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenres(t *testing.T) {
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "List",
+			method: "GET",
+			target: "/genres/",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(&models.GenreList{Genres: []models.Genre{
+					{ID: 1, Name: "adventure"},
+					{ID: 2, Name: "classics"},
+				}}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"genres\":[{\"id\":1,\"name\":\"adventure\"},{\"id\":2,\"name\":\"classics\"}]}\n",
+		},
+		{
+			name:   "Get",
+			method: "GET",
+			target: "/genres/3",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetGenreByID(gomock.Any(), 3).Return(models.Genre{ID: 3, Name: "fantasy"}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":3,\"name\":\"fantasy\"}\n",
+		},
+		{
+			name:                 "Get invalid id",
+			method:               "GET",
+			target:               "/genres/abc",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"invalid genre ID\"}\n",
+		},
+		{
+			name:   "Get not found",
+			method: "GET",
+			target: "/genres/9",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetGenreByID(gomock.Any(), 9).Return(models.Genre{}, db.ErrNoMatch)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
+		},
+		{
+			name:      "Create",
+			method:    "POST",
+			target:    "/genres/",
+			inputBody: `{"name": "poetry"}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().CreateGenre(gomock.Any(), &models.Genre{Name: "poetry"}).Return(4, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":4}\n",
+		},
+		{
+			name:                 "Create without name",
+			method:               "POST",
+			target:               "/genres/",
+			inputBody:            `{}`,
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"name is a required field\"}\n",
+		},
+		{
+			name:      "Create duplicate",
+			method:    "POST",
+			target:    "/genres/",
+			inputBody: `{"name": "fantasy"}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().CreateGenre(gomock.Any(), &models.Genre{Name: "fantasy"}).
+					Return(0, &db.ConflictError{Message: "genre name isn't unique"})
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: "{\"status_text\":\"Conflict\",\"message\":\"genre name isn't unique\"}\n",
+		},
+		{
+			name:      "Rename",
+			method:    "PUT",
+			target:    "/genres/2",
+			inputBody: `{"name": "classic literature"}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().UpdateGenreByID(gomock.Any(), 2, models.Genre{Name: "classic literature"}).Return(2, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":2}\n",
+		},
+		{
+			name:   "Delete",
+			method: "DELETE",
+			target: "/genres/4",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().DeleteGenreByID(gomock.Any(), 4).Return(nil)
+			},
+			expectedStatusCode:   http.StatusNoContent,
+			expectedResponseBody: ``,
+		},
+		{
+			name:   "Delete used genre",
+			method: "DELETE",
+			target: "/genres/1",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().DeleteGenreByID(gomock.Any(), 1).Return(db.ErrGenreInUse)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: "{\"status_text\":\"Conflict\",\"message\":\"genre is used by books\"}\n",
+		},
+		{
+			name:   "List error",
+			method: "GET",
+			target: "/genres/",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(nil, errors.New("connection refused"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: "{\"status_text\":\"Internal server error\",\"message\":\"connection refused\"}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			services := service.NewService(mockManager)
+			handler := NewHandler(services)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
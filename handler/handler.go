@@ -2,41 +2,95 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
 	"github.com/GlobantObrikosina/golang-rest-api/models"
 	"github.com/GlobantObrikosina/golang-rest-api/service"
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 )
 
 type Handler struct {
-	service *service.BooksManagerService
+	service        *service.BooksManagerService
+	requireIfMatch bool
+	logRequests    bool
+	health         *db.HealthMonitor
+	// now is the time feeds are stamped with.
+	now func() time.Time
 }
 
-func NewHandler(service *service.BooksManagerService) *Handler {
-	return &Handler{service: service}
+// Option changes the behaviour of a Handler created by NewHandler.
+type Option func(*Handler)
+
+// WithRequiredIfMatch makes writes to a book fail with 428 unless they carry
+// an If-Match header.
+func WithRequiredIfMatch() Option {
+	return func(h *Handler) {
+		h.requireIfMatch = true
+	}
+}
+
+// WithRequestLog logs every request with its status and duration.
+func WithRequestLog() Option {
+	return func(h *Handler) {
+		h.logRequests = true
+	}
+}
+
+// WithHealthMonitor makes GET /health report the database connection as
+// monitor last saw it.
+func WithHealthMonitor(monitor *db.HealthMonitor) Option {
+	return func(h *Handler) {
+		h.health = monitor
+	}
+}
+
+// WithClock stamps feeds with the time now returns instead of the current
+// time.
+func WithClock(now func() time.Time) Option {
+	return func(h *Handler) {
+		h.now = now
+	}
+}
+
+func NewHandler(service *service.BooksManagerService, options ...Option) *Handler {
+	h := &Handler{service: service, now: time.Now}
+	for _, option := range options {
+		option(h)
+	}
+	return h
 }
 
 func (h *Handler) InitRoutes() http.Handler {
 	router := chi.NewRouter()
+	if h.logRequests {
+		router.Use(middleware.Logger)
+	}
+	router.Use(recoverPanics)
 	router.MethodNotAllowed(MethodNotAllowedHandler)
 	router.NotFound(NotFoundHandler)
+	router.Get("/health", h.Health)
 	router.Route("/books", h.books)
+	router.Post("/books:batch", h.BatchBooks)
+	router.Route("/genres", h.genres)
+	router.Route("/authors", h.authors)
+	router.Route("/opds", h.opds)
+	router.Route("/feeds", h.feeds)
 	return router
 }
 
 func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-type", "application/json")
-	w.WriteHeader(405)
 	_ = render.Render(w, r, ErrMethodNotAllowed)
 }
 
 func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-type", "application/json")
-	w.WriteHeader(400)
-	_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("resource not found")))
+	_ = render.Render(w, r, NotFoundErrorRenderer(errors.New("resource not found")))
 }
 
 var bookIDKey = "bookID"
@@ -44,11 +98,18 @@ var bookIDKey = "bookID"
 func (h *Handler) books(router chi.Router) {
 	router.Get("/", h.GetAllBooks)
 	router.Post("/", h.CreateBook)
+	router.Post("/import", h.ImportBooks)
+	router.Get("/export", h.ExportBooks)
+	router.Get("/isbn/{isbn}", h.GetBookByISBN)
+	router.Get("/labels.svg", h.GetBookLabels)
 	router.Route("/{bookID}", func(router chi.Router) {
 		router.Use(h.BookContext)
 		router.Get("/", h.GetBook)
 		router.Put("/", h.UpdateBook)
+		router.Patch("/", h.PatchBook)
 		router.Delete("/", h.DeleteBookByID)
+		router.Get("/barcode.svg", h.GetBookBarcodeSVG)
+		router.Get("/barcode.png", h.GetBookBarcodePNG)
 	})
 }
 
@@ -67,41 +128,79 @@ func (h *Handler) BookContext(next http.Handler) http.Handler {
 }
 
 func (h *Handler) GetAllBooks(w http.ResponseWriter, r *http.Request) {
-	filterCondition := r.URL.Query()
-	if len(filterCondition) != 0 {
-		_, okGenre := filterCondition["genre"]
-		_, okName := filterCondition["name"]
-		if !okGenre && !okName {
-			_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("invalid filter condition")))
-			return
-		}
-		if okGenre {
-			genreID, err := strconv.Atoi(filterCondition.Get("genre"))
-			if err != nil || genreID < 1 || genreID > 3 {
-				_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("invalid filter condition")))
-				return
-			}
+	query := r.URL.Query()
+	page, err := parsePage(query)
+	if err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	fields, err := models.ParseFields(query.Get("fields"), models.Book{})
+	if err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	for _, param := range []string{"limit", "cursor", "sort", "fields"} {
+		query.Del(param)
+	}
+	filter, err := models.ParseBookFilter(query)
+	if err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	books, err := h.service.GetAllBooks(r.Context(), filter, page)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if fields == nil {
+		if err := render.Render(w, r, books); err != nil {
+			_ = render.Render(w, r, ErrorRenderer(err))
 		}
+		return
 	}
-	books, err := h.service.GetAllBooks(filterCondition)
+	sparse, err := selectBookListFields(books, fields)
 	if err != nil {
 		_ = render.Render(w, r, ServerErrorRenderer(err))
 		return
 	}
-	if err := render.Render(w, r, books); err != nil {
+	if err := render.Render(w, r, sparse); err != nil {
 		_ = render.Render(w, r, ErrorRenderer(err))
 	}
 }
 
+func parsePage(query url.Values) (models.Page, error) {
+	page := models.Page{Limit: models.DefaultPageLimit}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > models.MaxPageLimit {
+			return page, fmt.Errorf("limit has to be between 1 and %d", models.MaxPageLimit)
+		}
+		page.Limit = n
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		c, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = c
+	}
+	sort, err := models.ParseSort(query.Get("sort"))
+	if err != nil {
+		return page, err
+	}
+	page.Sort = sort
+	return page, nil
+}
+
 func (h *Handler) CreateBook(w http.ResponseWriter, r *http.Request) {
 	book := &models.Book{}
 	if err := render.Bind(r, book); err != nil {
 		_ = render.Render(w, r, ErrorRenderer(err))
 		return
 	}
-	id, err := h.service.CreateBook(book);
+	id, err := h.service.CreateBook(r.Context(), book)
 	if err != nil {
-		_ = render.Render(w, r, ServerErrorRenderer(err))
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
 		return
 	}
 	if err := render.Render(w, r, models.CreateBookResponse{BookID: id}); err != nil {
@@ -112,22 +211,75 @@ func (h *Handler) CreateBook(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) GetBook(w http.ResponseWriter, r *http.Request) {
 	bookID := r.Context().Value(bookIDKey).(int)
-	book, err := h.service.GetBookByID(bookID)
+	fields, err := models.ParseFields(r.URL.Query().Get("fields"), models.Book{})
+	if err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	book, err := h.service.GetBookByID(r.Context(), bookID)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	writeBook(w, r, book, fields)
+}
+
+// GetBookByISBN responds like GetBook, finding the book by an ISBN-10 or
+// ISBN-13 instead of its id.
+func (h *Handler) GetBookByISBN(w http.ResponseWriter, r *http.Request) {
+	isbn, err := models.NormalizeISBN(chi.URLParam(r, "isbn"))
+	if err != nil {
+		_ = render.Render(w, r, ErrorRenderer(fmt.Errorf("isbn %v", err)))
+		return
+	}
+	fields, err := models.ParseFields(r.URL.Query().Get("fields"), models.Book{})
 	if err != nil {
 		_ = render.Render(w, r, ErrorRenderer(err))
 		return
 	}
-	if err := render.Render(w, r, &book); err != nil {
+	book, err := h.service.GetBookByISBN(r.Context(), isbn)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	writeBook(w, r, book, fields)
+}
+
+// writeBook responds with book, or only its fields if there are any, tagged
+// with its version.
+func writeBook(w http.ResponseWriter, r *http.Request, book models.Book, fields models.Fields) {
+	w.Header().Set("ETag", bookETag(book.Version))
+	if header := r.Header.Get("If-None-Match"); header != "" && matchesETag(header, book.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if fields == nil {
+		if err := render.Render(w, r, &book); err != nil {
+			_ = render.Render(w, r, ServerErrorRenderer(err))
+		}
+		return
+	}
+	sparse, err := selectBookFields(book, fields)
+	if err != nil {
 		_ = render.Render(w, r, ServerErrorRenderer(err))
 		return
 	}
+	if err := render.Render(w, r, sparse); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
 }
 
 func (h *Handler) DeleteBookByID(w http.ResponseWriter, r *http.Request) {
 	bookID := r.Context().Value(bookIDKey).(int)
-	err := h.service.DeleteBookByID(bookID)
+	version, err := h.expectedVersion(r, bookID)
 	if err != nil {
-		_ = render.Render(w, r, ErrorRenderer(err))
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	err = h.service.DeleteBookByID(r.Context(), bookID, version)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
 	}
 	render.NoContent(w, r)
 }
@@ -139,11 +291,18 @@ func (h *Handler) UpdateBook(w http.ResponseWriter, r *http.Request) {
 		_ = render.Render(w, r, ErrorRenderer(err))
 		return
 	}
-	newBookID, err := h.service.UpdateBookByID(bookID, bookData)
+	version, err := h.expectedVersion(r, bookID)
 	if err != nil {
-		_ = render.Render(w, r, ErrorRenderer(err))
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	bookData.Version = version
+	newBookID, err := h.service.UpdateBookByID(r.Context(), bookID, bookData)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
 		return
 	}
+	setWrittenETag(w, bookData.Version)
 	if err := render.Render(w, r, models.CreateBookResponse{BookID: newBookID}); err != nil {
 		_ = render.Render(w, r, ServerErrorRenderer(err))
 		return
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
 	"github.com/GlobantObrikosina/golang-rest-api/models"
 	"github.com/GlobantObrikosina/golang-rest-api/service"
 	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
@@ -30,23 +32,193 @@ func TestGetAllBooks(t *testing.T) {
 			filterCondition:      map[string][]string{"avadakedavra": {"7"}},
 			mockBehavior:         func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
 			expectedStatusCode:   http.StatusBadRequest,
-			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"invalid filter condition\"}\n",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"avadakedavra isn't a known filter\"}\n",
 		},
 		{
 			name:                 "Invalid genre id in filter condition",
 			filterCondition:      map[string][]string{"genre": {"0"}},
 			mockBehavior:         func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
 			expectedStatusCode:   http.StatusBadRequest,
-			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"invalid filter condition\"}\n",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"genre has to be a positive integer\"}\n",
 		},
 		{
 			name:            "Get All Ok",
 			filterCondition: map[string][]string{},
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {
-				r.EXPECT().GetAllBooks(filterCondition).Return(&models.BookList{}, nil)
+				r.EXPECT().GetAllBooks(gomock.Any(), models.BookFilter{}, models.Page{Limit: models.DefaultPageLimit}).
+					Return(&models.BookList{}, nil)
 			},
 			expectedStatusCode:   http.StatusOK,
-			expectedResponseBody: "{\"books\":null}\n",
+			expectedResponseBody: "{\"books\":null,\"has_more\":false}\n",
+		},
+		{
+			name:                 "Invalid author id in filter condition",
+			filterCondition:      map[string][]string{"author": {"abc"}},
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"author has to be a positive integer\"}\n",
+		},
+		{
+			name:            "Filter by author",
+			filterCondition: map[string][]string{"author": {"7"}},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {
+				filter := models.BookFilter{Conditions: []models.Condition{
+					{Field: "author", Op: models.OpEq, Values: []interface{}{7}},
+				}}
+				r.EXPECT().GetAllBooks(gomock.Any(), filter, models.Page{Limit: models.DefaultPageLimit}).
+					Return(&models.BookList{Books: []models.Book{{
+						ID: 1, Name: "hello", Genre: 1, Price: 1.5, Amount: 2,
+						Authors: []models.AuthorSummary{{ID: 7, Name: "Jules Verne"}}, Available: true,
+					}}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"books\":[{\"id\":1,\"name\":\"hello\",\"genre\":1,\"price\":1.5,\"amount\":2," +
+				"\"authors\":[{\"id\":7,\"name\":\"Jules Verne\"}],\"available\":true,\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"}],\"has_more\":false}\n",
+		},
+		{
+			name:                 "Empty search",
+			filterCondition:      map[string][]string{"q": {"  "}},
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"q has to have between 1 and 100 characters\"}\n",
+		},
+		{
+			name:            "Search",
+			filterCondition: map[string][]string{"q": {" hobbit "}},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {
+				r.EXPECT().GetAllBooks(gomock.Any(), models.BookFilter{Search: "hobbit"}, models.Page{Limit: models.DefaultPageLimit}).
+					Return(&models.BookList{Books: []models.Book{{
+						ID: 1, Name: "The Hobbit", Genre: 3, Price: 9.5, Amount: 2, Available: true,
+						Rank: 0.75, Highlight: "The <mark>Hobbit</mark>",
+					}}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"books\":[{\"id\":1,\"name\":\"The Hobbit\",\"genre\":3,\"price\":9.5,\"amount\":2,\"available\":true,\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"," +
+				"\"rank\":0.75,\"highlight\":\"The \\u003cmark\\u003eHobbit\\u003c/mark\\u003e\"}],\"has_more\":false}\n",
+		},
+		{
+			name: "Filter by price range and genres",
+			filterCondition: map[string][]string{
+				"price[gte]": {"10"},
+				"price[lt]":  {"30"},
+				"genre":      {"1,3"},
+				"amount[gt]": {"0"},
+			},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {
+				filter := models.BookFilter{Conditions: []models.Condition{
+					{Field: "amount", Op: models.OpGt, Values: []interface{}{0}},
+					{Field: "genre", Op: models.OpEq, Values: []interface{}{1, 3}},
+					{Field: "price", Op: models.OpGte, Values: []interface{}{10.0}},
+					{Field: "price", Op: models.OpLt, Values: []interface{}{30.0}},
+				}}
+				r.EXPECT().GetAllBooks(gomock.Any(), filter, models.Page{Limit: models.DefaultPageLimit}).
+					Return(&models.BookList{}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"books\":null,\"has_more\":false}\n",
+		},
+		{
+			name: "Several invalid filters",
+			filterCondition: map[string][]string{
+				"price[like]": {"10"},
+				"amount[gt]":  {"1,2"},
+			},
+			mockBehavior:       func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"amount[gt] takes a single value; " +
+				"price doesn't support the like operator\"}\n",
+		},
+		{
+			name: "Sorted sparse list",
+			filterCondition: map[string][]string{
+				"sort":   {"price,-name"},
+				"fields": {"price,name,authors"},
+			},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {
+				page := models.Page{
+					Limit: models.DefaultPageLimit,
+					Sort:  models.Sort{{Field: "price"}, {Field: "name", Desc: true}},
+				}
+				r.EXPECT().GetAllBooks(gomock.Any(), models.BookFilter{}, page).
+					Return(&models.BookList{Books: []models.Book{
+						{ID: 1, Name: "hello", Genre: 1, Price: 1.5, Amount: 2,
+							Authors: []models.AuthorSummary{{ID: 7, Name: "Jules Verne"}}},
+						{ID: 2, Name: "bye", Genre: 1, Price: 2.5, Amount: 2},
+					}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"books\":[{\"name\":\"hello\",\"price\":1.5,\"authors\":[{\"id\":7,\"name\":\"Jules Verne\"}]}," +
+				"{\"name\":\"bye\",\"price\":2.5}],\"has_more\":false}\n",
+		},
+		{
+			name:                 "Invalid sort",
+			filterCondition:      map[string][]string{"sort": {"-version"}},
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"sort can't use \\\"version\\\"\"}\n",
+		},
+		{
+			name:                 "Invalid fields",
+			filterCondition:      map[string][]string{"fields": {"id,version"}},
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"fields can't contain \\\"version\\\"\"}\n",
+		},
+		{
+			name:            "Out of stock",
+			filterCondition: map[string][]string{"availability": {"out_of_stock"}},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {
+				filter := models.BookFilter{Availability: models.AvailabilityOutOfStock}
+				r.EXPECT().GetAllBooks(gomock.Any(), filter, models.Page{Limit: models.DefaultPageLimit}).
+					Return(&models.BookList{Books: []models.Book{{ID: 1, Name: "hello", Genre: 1, Price: 1.5}}}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"books\":[{\"id\":1,\"name\":\"hello\",\"genre\":1,\"price\":1.5,\"amount\":0," +
+				"\"available\":false,\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"}],\"has_more\":false}\n",
+		},
+		{
+			name:               "Invalid availability",
+			filterCondition:    map[string][]string{"availability": {"sold"}},
+			mockBehavior:       func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":" +
+				"\"availability has to be one of in_stock, out_of_stock, all\"}\n",
+		},
+		{
+			name:                 "Invalid limit",
+			filterCondition:      map[string][]string{"limit": {"1000"}},
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"limit has to be between 1 and 100\"}\n",
+		},
+		{
+			name:                 "Invalid cursor",
+			filterCondition:      map[string][]string{"cursor": {"not-a-cursor"}},
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"invalid cursor\"}\n",
+		},
+		{
+			name: "Get page Ok",
+			filterCondition: map[string][]string{
+				"genre":  {"1"},
+				"limit":  {"1"},
+				"cursor": {models.Cursor{ID: 5}.Encode()},
+			},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, filterCondition map[string][]string) {
+				filter := models.BookFilter{Conditions: []models.Condition{
+					{Field: "genre", Op: models.OpEq, Values: []interface{}{1}},
+				}}
+				r.EXPECT().GetAllBooks(gomock.Any(), filter, models.Page{Limit: 1, Cursor: models.Cursor{ID: 5}}).
+					Return(&models.BookList{
+						Books:      []models.Book{{ID: 4, Name: "hello", Genre: 1, Price: 1.5, Amount: 2, Available: true}},
+						NextCursor: models.Cursor{ID: 4}.Encode(),
+						HasMore:    true,
+					}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"books\":[{\"id\":4,\"name\":\"hello\",\"genre\":1,\"price\":1.5,\"amount\":2,\"available\":true,\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"}]," +
+				"\"next_cursor\":\"" + models.Cursor{ID: 4}.Encode() + "\",\"has_more\":true}\n",
 		},
 	}
 	for _, test := range tests {
@@ -58,7 +230,7 @@ func TestGetAllBooks(t *testing.T) {
 			test.mockBehavior(mockManager, test.filterCondition)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := chi.NewRouter()
 			r.Get("/books", handler.GetAllBooks)
@@ -98,11 +270,29 @@ func TestCreateBook(t *testing.T) {
 				Amount: 1,
 			},
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, book *models.Book) {
-				r.EXPECT().CreateBook(book).Return(0, nil)
+				r.EXPECT().GetGenreByID(gomock.Any(), book.Genre).Return(models.Genre{ID: book.Genre}, nil)
+				r.EXPECT().CreateBook(gomock.Any(), book).Return(0, nil)
 			},
 			expectedStatusCode:   http.StatusOK,
 			expectedResponseBody: "{\"id\":0}\n",
 		},
+		{
+			name:      "With authors",
+			inputBody: `{"name": "Book1", "genre": 1, "price": 1.1, "amount": 1, "authors": [{"id": 7}]}`,
+			inputBook: &models.Book{
+				Name:    "Book1",
+				Genre:   1,
+				Price:   1.1,
+				Amount:  1,
+				Authors: []models.AuthorSummary{{ID: 7}},
+			},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, book *models.Book) {
+				r.EXPECT().GetGenreByID(gomock.Any(), book.Genre).Return(models.Genre{ID: book.Genre}, nil)
+				r.EXPECT().CreateBook(gomock.Any(), book).Return(3, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":3}\n",
+		},
 		{
 			name:                 "Fields missing",
 			inputBody:            `{"price": 67.88, "genre": 1, "amount": 5}`,
@@ -111,11 +301,27 @@ func TestCreateBook(t *testing.T) {
 			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"name is a required field\"}\n",
 		},
 		{
-			name:                 "Invalid genre",
-			inputBody:            `{"name": "hello", "price": 67.88, "genre": 112, "amount": 7}`,
+			name:                 "Several fields invalid",
+			inputBody:            `{"price": -1, "genre": 1, "amount": -5}`,
 			mockBehavior:         func(r *mock.MockDatabaseBooksManager, book *models.Book) {},
 			expectedStatusCode:   http.StatusBadRequest,
-			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"genre has to be between 1 and 3\"}\n",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"name is a required field; price can't be less than 0; amount can't be less than 0\"}\n",
+		},
+		{
+			name:      "Invalid genre",
+			inputBody: `{"name": "hello", "price": 67.88, "genre": 112, "amount": 7}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, book *models.Book) {
+				r.EXPECT().GetGenreByID(gomock.Any(), 112).Return(models.Genre{}, db.ErrNoMatch)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"genre doesn't exist\"}\n",
+		},
+		{
+			name:                 "Missing genre",
+			inputBody:            `{"name": "hello", "price": 67.88, "amount": 7}`,
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, book *models.Book) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"genre is a required field\"}\n",
 		},
 		{
 			name:                 "Invalid genre",
@@ -142,11 +348,44 @@ func TestCreateBook(t *testing.T) {
 				Amount: 7,
 			},
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, book *models.Book) {
-				r.EXPECT().CreateBook(book).Return(0, errors.New("input book name isn't unique"))
+				r.EXPECT().GetGenreByID(gomock.Any(), book.Genre).Return(models.Genre{ID: book.Genre}, nil)
+				r.EXPECT().CreateBook(gomock.Any(), book).Return(0, errors.New("input book name isn't unique"))
 			},
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedResponseBody: "{\"status_text\":\"Internal server error\",\"message\":\"input book name isn't unique\"}\n",
 		},
+		{
+			name:      "Conflicting name",
+			inputBody: `{"name": "hello", "price": 67.88, "genre": 1, "amount": 7}`,
+			inputBook: &models.Book{
+				Name:   "hello",
+				Price:  67.88,
+				Genre:  1,
+				Amount: 7,
+			},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, book *models.Book) {
+				r.EXPECT().GetGenreByID(gomock.Any(), book.Genre).Return(models.Genre{ID: book.Genre}, nil)
+				r.EXPECT().CreateBook(gomock.Any(), book).Return(0, &db.ConflictError{Message: "book name isn't unique"})
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: "{\"status_text\":\"Conflict\",\"message\":\"book name isn't unique\"}\n",
+		},
+		{
+			name:      "Unknown genre",
+			inputBody: `{"name": "hello", "price": 67.88, "genre": 2, "amount": 7}`,
+			inputBook: &models.Book{
+				Name:   "hello",
+				Price:  67.88,
+				Genre:  2,
+				Amount: 7,
+			},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, book *models.Book) {
+				r.EXPECT().GetGenreByID(gomock.Any(), book.Genre).Return(models.Genre{ID: book.Genre}, nil)
+				r.EXPECT().CreateBook(gomock.Any(), book).Return(0, &db.ValidationError{Message: "genre doesn't exist"})
+			},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: "{\"status_text\":\"Unprocessable entity\",\"message\":\"genre doesn't exist\"}\n",
+		},
 	}
 
 	for _, test := range tests {
@@ -159,7 +398,7 @@ func TestCreateBook(t *testing.T) {
 			test.mockBehavior(mockManager, test.inputBook)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := chi.NewRouter()
 			r.Post("/books", handler.CreateBook)
@@ -198,19 +437,19 @@ func TestDeleteBook(t *testing.T) {
 			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"invalid book ID\"}\n",
 		},
 		{
-			name:    "Id invalid",
+			name:    "Id not found",
 			inputId: 10,
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
-				r.EXPECT().DeleteBookByID(id).Return(errors.New("no matching record"))
+				r.EXPECT().DeleteBookByID(gomock.Any(), id, 0).Return(db.ErrNoMatch)
 			},
-			expectedStatusCode:   http.StatusBadRequest,
-			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"no matching record\"}\n",
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
 		},
 		{
 			name:    "Id OK",
 			inputId: 1,
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
-				r.EXPECT().DeleteBookByID(id).Return(nil)
+				r.EXPECT().DeleteBookByID(gomock.Any(), id, 0).Return(nil)
 			},
 			expectedStatusCode:   http.StatusNoContent,
 			expectedResponseBody: ``,
@@ -226,7 +465,7 @@ func TestDeleteBook(t *testing.T) {
 			test.mockBehavior(mockManager, test.inputId)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := chi.NewRouter()
 			r.Route("/{bookID}", func(router chi.Router) {
@@ -259,6 +498,7 @@ func TestGetBook(t *testing.T) {
 		inputId              int
 		falseId              string
 		useFalseID           bool
+		query                string
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
 		expectedResponseBody string
@@ -275,7 +515,24 @@ func TestGetBook(t *testing.T) {
 			name:    "Id OK",
 			inputId: 1,
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
-				r.EXPECT().GetBookByID(id).Return(models.Book{
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{
+					ID:        1,
+					Name:      "hello",
+					Price:     4.32,
+					Genre:     2,
+					Amount:    9,
+					Available: true,
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":1,\"name\":\"hello\",\"genre\":2,\"price\":4.32,\"amount\":9,\"available\":true,\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"}\n",
+		},
+		{
+			name:    "Sparse fieldset",
+			inputId: 1,
+			query:   "fields=id,name",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{
 					ID:     1,
 					Name:   "hello",
 					Price:  4.32,
@@ -284,16 +541,51 @@ func TestGetBook(t *testing.T) {
 				}, nil)
 			},
 			expectedStatusCode:   http.StatusOK,
-			expectedResponseBody: "{\"id\":1,\"name\":\"hello\",\"genre\":2,\"price\":4.32,\"amount\":9}\n",
+			expectedResponseBody: "{\"id\":1,\"name\":\"hello\"}\n",
+		},
+		{
+			name:                 "Invalid fields",
+			inputId:              1,
+			query:                "fields=id,publisher",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, id int) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"fields can't contain \\\"publisher\\\"\"}\n",
 		},
 		{
 			name:    "Id not found",
 			inputId: 1,
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
-				r.EXPECT().GetBookByID(id).Return(models.Book{}, fmt.Errorf("no matching record"))
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{}, db.ErrNoMatch)
 			},
-			expectedStatusCode:   http.StatusBadRequest,
-			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"no matching record\"}\n",
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
+		},
+		{
+			name:    "Internal error",
+			inputId: 1,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{}, errors.New("connection refused"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: "{\"status_text\":\"Internal server error\",\"message\":\"connection refused\"}\n",
+		},
+		{
+			name:    "Query timed out",
+			inputId: 1,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{}, context.DeadlineExceeded)
+			},
+			expectedStatusCode:   http.StatusServiceUnavailable,
+			expectedResponseBody: "{\"status_text\":\"Service unavailable\",\"message\":\"context deadline exceeded\"}\n",
+		},
+		{
+			name:    "Client went away",
+			inputId: 1,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{}, context.Canceled)
+			},
+			expectedStatusCode:   499,
+			expectedResponseBody: "{\"status_text\":\"Client closed request\",\"message\":\"context canceled\"}\n",
 		},
 	}
 
@@ -307,7 +599,7 @@ func TestGetBook(t *testing.T) {
 			test.mockBehavior(mockManager, test.inputId)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := chi.NewRouter()
 			r.Route("/{bookID}", func(router chi.Router) {
@@ -320,6 +612,9 @@ func TestGetBook(t *testing.T) {
 			} else {
 				target = fmt.Sprintf("/%v", test.inputId)
 			}
+			if test.query != "" {
+				target += "?" + test.query
+			}
 
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest("GET", target, nil)
@@ -332,6 +627,68 @@ func TestGetBook(t *testing.T) {
 	}
 }
 
+func TestGetBookByISBN(t *testing.T) {
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		isbn                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedETag         string
+		expectedResponseBody string
+	}{
+		{
+			name: "ISBN-10",
+			isbn: "0-306-40615-2",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByISBN(gomock.Any(), "9780306406157").Return(models.Book{
+					ID: 1, Name: "hello", ISBN: "9780306406157", Genre: 2, Price: 4.5, Version: 2,
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"2"`,
+			expectedResponseBody: "{\"id\":1,\"name\":\"hello\",\"isbn\":\"9780306406157\",\"genre\":2,\"price\":4.5,\"amount\":0," +
+				"\"available\":false,\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"}\n",
+		},
+		{
+			name: "Not found",
+			isbn: "9780306406157",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetBookByISBN(gomock.Any(), "9780306406157").Return(models.Book{}, db.ErrNoMatch)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
+		},
+		{
+			name:                 "Invalid checksum",
+			isbn:                 "9780306406158",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"isbn has an invalid check digit\"}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			handler := NewHandler(service.NewService(mockManager))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/books/isbn/"+test.isbn, nil)
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedETag, w.Header().Get("ETag"))
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
 func TestUpdateBook(t *testing.T) {
 	type mockBehavior func(s *mock.MockDatabaseBooksManager, id int, book models.Book)
 	tests := []struct {
@@ -358,18 +715,32 @@ func TestUpdateBook(t *testing.T) {
 			inputId:   1,
 			inputBody: `{"name": "Book1", "genre": 1, "price": -12.12, "amount": 0}`,
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int, book models.Book) {
-				//r.EXPECT().UpdateBookByID(id, book).Return(0, errors.New("price can't be less than 0"))
+				//r.EXPECT().UpdateBookByID(gomock.Any(), id, book).Return(0, errors.New("price can't be less than 0"))
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"price can't be less than 0\"}\n",
 		},
 		{
-			name:                 "Update invalid genre input",
-			inputId:              1,
-			inputBody:            `{"name": "Book1", "genre": 111, "price": 12.12, "amount": 0}`,
-			mockBehavior:         func(r *mock.MockDatabaseBooksManager, id int, book models.Book) {},
+			name:      "Update invalid genre input",
+			inputId:   1,
+			inputBody: `{"name": "Book1", "genre": 111, "price": 12.12, "amount": 0}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int, book models.Book) {
+				r.EXPECT().GetGenreByID(gomock.Any(), 111).Return(models.Genre{}, db.ErrNoMatch)
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{ID: id}, nil)
+			},
 			expectedStatusCode:   http.StatusBadRequest,
-			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"genre has to be between 1 and 3\"}\n",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"genre doesn't exist\"}\n",
+		},
+		{
+			name:      "Update id not found with invalid genre",
+			inputId:   9,
+			inputBody: `{"name": "Book1", "genre": 111, "price": 12.12, "amount": 0}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int, book models.Book) {
+				r.EXPECT().GetGenreByID(gomock.Any(), 111).Return(models.Genre{}, db.ErrNoMatch)
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{}, db.ErrNoMatch)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
 		},
 		{
 			name:                 "Update invalid amount input",
@@ -390,10 +761,28 @@ func TestUpdateBook(t *testing.T) {
 				Amount: 0,
 			},
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int, book models.Book) {
-				r.EXPECT().UpdateBookByID(id, book).Return(0, errors.New("id not found"))
+				r.EXPECT().GetGenreByID(gomock.Any(), book.Genre).Return(models.Genre{ID: book.Genre}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), id, book).Return(0, db.ErrNoMatch)
 			},
-			expectedStatusCode:   http.StatusBadRequest,
-			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"id not found\"}\n",
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
+		},
+		{
+			name:      "Update name conflict",
+			inputId:   1,
+			inputBody: `{"name": "Book1", "genre": 1, "price": 0, "amount": 0}`,
+			inputBook: models.Book{
+				Name:   "Book1",
+				Genre:  1,
+				Price:  0,
+				Amount: 0,
+			},
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int, book models.Book) {
+				r.EXPECT().GetGenreByID(gomock.Any(), book.Genre).Return(models.Genre{ID: book.Genre}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), id, book).Return(0, &db.ConflictError{Message: "book name isn't unique"})
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: "{\"status_text\":\"Conflict\",\"message\":\"book name isn't unique\"}\n",
 		},
 		{
 			name:      "Update ok",
@@ -406,7 +795,8 @@ func TestUpdateBook(t *testing.T) {
 				Amount: 0,
 			},
 			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int, book models.Book) {
-				r.EXPECT().UpdateBookByID(id, book).Return(id, nil)
+				r.EXPECT().GetGenreByID(gomock.Any(), book.Genre).Return(models.Genre{ID: book.Genre}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), id, book).Return(id, nil)
 			},
 			expectedStatusCode:   http.StatusOK,
 			expectedResponseBody: "{\"id\":1}\n",
@@ -421,7 +811,7 @@ func TestUpdateBook(t *testing.T) {
 			test.mockBehavior(mockManager, test.inputId, test.inputBook)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := chi.NewRouter()
 			r.Route("/{bookID}", func(router chi.Router) {
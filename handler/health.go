@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+// Health answers 200 while the database is reachable and 503 while it isn't,
+// for load balancers and orchestrators to route around a broken instance.
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	health := &models.Health{Status: models.HealthUp}
+	if h.health != nil {
+		database := h.health.Health()
+		health.Status, health.Database = database.Status, &database
+	}
+	if health.Status != models.HealthUp {
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	_ = render.Render(w, r, health)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name               string
+		monitored          bool
+		pings              []error
+		expectedStatusCode int
+		expectedStatus     string
+		expectedDatabase   *models.DatabaseHealth
+	}{
+		{
+			name:               "Unmonitored",
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     models.HealthUp,
+		},
+		{
+			name:               "Database up",
+			monitored:          true,
+			pings:              []error{nil},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     models.HealthUp,
+			expectedDatabase:   &models.DatabaseHealth{Status: models.HealthUp},
+		},
+		{
+			name:               "Database down",
+			monitored:          true,
+			pings:              []error{errors.New("connection refused")},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedStatus:     models.HealthDown,
+			expectedDatabase:   &models.DatabaseHealth{Status: models.HealthDown, Error: "connection refused"},
+		},
+		{
+			name:               "Database reconnected",
+			monitored:          true,
+			pings:              []error{errors.New("connection refused"), nil},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     models.HealthUp,
+			expectedDatabase:   &models.DatabaseHealth{Status: models.HealthUp, Reconnects: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			var options []Option
+			if test.monitored {
+				monitor := db.NewHealthMonitor(mockManager, nil)
+				for _, err := range test.pings {
+					mockManager.EXPECT().Ping(gomock.Any()).Return(err)
+					monitor.Check(context.Background())
+				}
+				options = append(options, WithHealthMonitor(monitor))
+			}
+			handler := NewHandler(service.NewService(mockManager), options...)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/health", nil)
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			var health models.Health
+			if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &health)) {
+				assert.Equal(t, test.expectedStatus, health.Status)
+				if test.expectedDatabase == nil {
+					assert.Nil(t, health.Database)
+				} else if assert.NotNil(t, health.Database) {
+					assert.Equal(t, test.expectedDatabase.Status, health.Database.Status)
+					assert.Equal(t, test.expectedDatabase.Error, health.Database.Error)
+					assert.Equal(t, test.expectedDatabase.Reconnects, health.Database.Reconnects)
+					assert.False(t, health.Database.CheckedAt.IsZero())
+				}
+			}
+		})
+	}
+}
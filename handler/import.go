@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/catalog"
+	"github.com/go-chi/render"
+	"mime"
+	"net/http"
+)
+
+// ImportBooks upserts the books of a CSV or NDJSON catalog streamed in the
+// request body and responds with a report per row. The format comes from
+// the format parameter or else from the Content-Type header.
+func (h *Handler) ImportBooks(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		format = catalog.FormatOf(mediaType)
+		if format == "" {
+			_ = render.Render(w, r, UnsupportedMediaTypeErrorRenderer(
+				fmt.Errorf("content type has to be %s or %s", catalog.ContentTypeCSV, catalog.ContentTypeNDJSON)))
+			return
+		}
+	}
+	reader, err := catalog.NewReader(r.Body, format)
+	if err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	report, err := h.service.ImportBooks(r.Context(), reader)
+	if errors.Is(err, catalog.ErrMalformed) {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if err := render.Render(w, r, report); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestImportBooks(t *testing.T) {
+	genres := &models.GenreList{Genres: []models.Genre{{ID: 1, Name: "adventure"}, {ID: 3, Name: "Fantasy"}}}
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		target               string
+		contentType          string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:        "CSV",
+			target:      "/books/import",
+			contentType: "text/csv; charset=utf-8",
+			inputBody: "name,genre,price,amount\n" +
+				"The Hobbit,fantasy,9.5,3\n" +
+				"Emma,adventure,4,1\n" +
+				"Dune,sci-fi,5,1\n" +
+				",fantasy,-1,1\n" +
+				"Taken,fantasy,1,1\n",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+				r.EXPECT().UpsertBook(gomock.Any(), &models.Book{Name: "The Hobbit", Genre: 3, Price: 9.5, Amount: 3}).
+					DoAndReturn(func(_ interface{}, book *models.Book) (bool, error) {
+						book.ID = 10
+						return true, nil
+					})
+				r.EXPECT().UpsertBook(gomock.Any(), &models.Book{Name: "Emma", Genre: 1, Price: 4, Amount: 1}).
+					DoAndReturn(func(_ interface{}, book *models.Book) (bool, error) {
+						book.ID = 4
+						return false, nil
+					})
+				r.EXPECT().UpsertBook(gomock.Any(), &models.Book{Name: "Taken", Genre: 3, Price: 1, Amount: 1}).
+					Return(false, &db.ConflictError{Message: "book name isn't unique"})
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"created\":1,\"updated\":1,\"rejected\":3,\"rows\":[" +
+				"{\"line\":2,\"status\":\"created\",\"id\":10,\"name\":\"The Hobbit\"}," +
+				"{\"line\":3,\"status\":\"updated\",\"id\":4,\"name\":\"Emma\"}," +
+				"{\"line\":4,\"status\":\"rejected\",\"name\":\"Dune\",\"message\":\"genre \\\"sci-fi\\\" doesn't exist\"," +
+				"\"errors\":[{\"field\":\"genre\",\"message\":\"genre \\\"sci-fi\\\" doesn't exist\"}]}," +
+				"{\"line\":5,\"status\":\"rejected\",\"message\":\"name is a required field; price can't be less than 0\"," +
+				"\"errors\":[{\"field\":\"name\",\"message\":\"name is a required field\"}," +
+				"{\"field\":\"price\",\"message\":\"price can't be less than 0\"}]}," +
+				"{\"line\":6,\"status\":\"rejected\",\"name\":\"Taken\",\"message\":\"book name isn't unique\"}]}\n",
+		},
+		{
+			name:      "NDJSON from format parameter",
+			target:    "/books/import?format=ndjson",
+			inputBody: `{"name":"Emma","genre":"adventure","price":4,"amount":1}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+				r.EXPECT().UpsertBook(gomock.Any(), gomock.Any()).Return(true, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: "{\"created\":1,\"updated\":0,\"rejected\":0,\"rows\":[" +
+				"{\"line\":1,\"status\":\"created\",\"name\":\"Emma\"}]}\n",
+		},
+		{
+			name:        "Malformed catalog",
+			target:      "/books/import",
+			contentType: "text/csv",
+			inputBody:   "title,genre\n",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"malformed catalog: unknown column \\\"title\\\"\"}\n",
+		},
+		{
+			name:        "Database error",
+			target:      "/books/import",
+			contentType: "application/x-ndjson",
+			inputBody:   `{"name":"Emma","genre":"adventure"}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+				r.EXPECT().UpsertBook(gomock.Any(), gomock.Any()).Return(false, errors.New("connection refused"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: "{\"status_text\":\"Internal server error\",\"message\":\"connection refused\"}\n",
+		},
+		{
+			name:                 "Unsupported content type",
+			target:               "/books/import",
+			contentType:          "application/json",
+			inputBody:            `[]`,
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusUnsupportedMediaType,
+			expectedResponseBody: "{\"status_text\":\"Unsupported media type\",\"message\":\"content type has to be text/csv or application/x-ndjson\"}\n",
+		},
+		{
+			name:                 "Unknown format",
+			target:               "/books/import?format=xlsx",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"format has to be one of csv, ndjson\"}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			services := service.NewService(mockManager)
+			handler := NewHandler(services)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", test.target, bytes.NewBufferString(test.inputBody))
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestMemoryDatabase runs requests against the real service and an
+// in-memory database, each step seeing what the previous ones did.
+func TestMemoryDatabase(t *testing.T) {
+	steps := []struct {
+		name                 string
+		method               string
+		target               string
+		body                 string
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:               "Create",
+			method:             "POST",
+			target:             "/books",
+			body:               `{"name":"Emma","genre":2,"price":8,"amount":2}`,
+			expectedStatusCode: 200,
+		},
+		{
+			name:               "Create out of stock",
+			method:             "POST",
+			target:             "/books",
+			body:               `{"name":"Dune","genre":3,"price":12,"amount":0}`,
+			expectedStatusCode: 200,
+		},
+		{
+			name:                 "Duplicate name",
+			method:               "POST",
+			target:               "/books",
+			body:                 `{"name":"Emma","genre":1,"price":8,"amount":2}`,
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"status_text":"Conflict","message":"book name isn't unique"}`,
+		},
+		{
+			name:                 "Unknown genre",
+			method:               "POST",
+			target:               "/books",
+			body:                 `{"name":"Ulysses","genre":9,"price":8,"amount":2}`,
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"status_text":"Bad request","message":"genre doesn't exist"}`,
+		},
+		{
+			name:                 "List in stock",
+			method:               "GET",
+			target:               "/books?fields=id,name",
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"books":[{"id":1,"name":"Emma"}],"has_more":false}`,
+		},
+		{
+			name:                 "List all",
+			method:               "GET",
+			target:               "/books?fields=id,name&availability=all",
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"books":[{"id":2,"name":"Dune"},{"id":1,"name":"Emma"}],"has_more":false}`,
+		},
+		{
+			name:                 "Genre in use",
+			method:               "DELETE",
+			target:               "/genres/3",
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"status_text":"Conflict","message":"genre is used by books"}`,
+		},
+		{
+			name:               "Delete",
+			method:             "DELETE",
+			target:             "/books/2",
+			expectedStatusCode: 204,
+		},
+		{
+			name:                 "Deleted",
+			method:               "GET",
+			target:               "/books/2",
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"status_text":"Not found","message":"no matching record"}`,
+		},
+	}
+
+	router := NewHandler(service.NewService(db.NewMemoryDatabase())).InitRoutes()
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(step.method, step.target, bytes.NewBufferString(step.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, step.expectedStatusCode, w.Code)
+			if step.expectedResponseBody != "" {
+				assert.JSONEq(t, step.expectedResponseBody, w.Body.String())
+			}
+		})
+	}
+}
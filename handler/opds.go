@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/opds"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	opdsRoot       = "/opds"
+	opdsBooks      = opdsRoot + "/books"
+	opdsOpenSearch = opdsRoot + "/opensearch.xml"
+
+	// opdsID prefixes the permanent ids of feeds and entries.
+	opdsID = "urn:bookstore:"
+)
+
+func (h *Handler) opds(router chi.Router) {
+	router.Get("/", h.GetOPDSRoot)
+	router.Get("/books", h.GetOPDSBooks)
+	router.Get("/opensearch.xml", h.GetOPDSOpenSearch)
+	router.With(h.GenreContext).Get("/genres/{genreID}", h.GetOPDSGenre)
+}
+
+// GetOPDSRoot is the start of the OPDS catalog: a navigation feed with all
+// books and a subsection per genre.
+func (h *Handler) GetOPDSRoot(w http.ResponseWriter, r *http.Request) {
+	genres, err := h.service.GetAllGenres(r.Context())
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	updated := h.now().UTC()
+	feed := opds.Feed{
+		ID:      opdsID + "catalog",
+		Title:   "Books",
+		Updated: updated,
+		Links: []opds.Link{
+			{Rel: opds.RelSelf, Href: opdsRoot, Type: opds.NavigationType},
+			{Rel: opds.RelStart, Href: opdsRoot, Type: opds.NavigationType},
+			{Rel: opds.RelSearch, Href: opdsOpenSearch, Type: opds.OpenSearchType},
+		},
+		Entries: []opds.Entry{{
+			ID:      opdsID + "books",
+			Title:   "All books",
+			Updated: updated,
+			Content: opds.TextContent("Every book in stock"),
+			Links:   []opds.Link{{Rel: opds.RelSubsection, Href: opdsBooks, Type: opds.AcquisitionType}},
+		}},
+	}
+	for _, genre := range genres.Genres {
+		feed.Entries = append(feed.Entries, opds.Entry{
+			ID:      opdsID + "genres:" + strconv.Itoa(genre.ID),
+			Title:   genre.Name,
+			Updated: updated,
+			Content: opds.TextContent(fmt.Sprintf("Books in %s", genre.Name)),
+			Links:   []opds.Link{{Rel: opds.RelSubsection, Href: opdsGenre(genre.ID), Type: opds.AcquisitionType}},
+		})
+	}
+	writeXML(w, r, opds.NavigationType, feed)
+}
+
+// GetOPDSBooks is an acquisition feed of all books in stock, or of the ones
+// matching the q search parameter.
+func (h *Handler) GetOPDSBooks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.BookFilter{}
+	title := "All books"
+	links := url.Values{}
+	if q, ok := query["q"]; ok {
+		parsed, err := models.ParseBookFilter(url.Values{"q": q})
+		if err != nil {
+			_ = render.Render(w, r, ErrorRenderer(err))
+			return
+		}
+		filter = parsed
+		title = fmt.Sprintf("Search results for %q", filter.Search)
+		links.Set("q", filter.Search)
+	}
+	h.writeOPDSBooks(w, r, opdsID+"books", title, opdsBooks, links, filter)
+}
+
+// GetOPDSGenre is an acquisition feed of the books in stock of a genre.
+func (h *Handler) GetOPDSGenre(w http.ResponseWriter, r *http.Request) {
+	genreID := r.Context().Value(genreIDKey).(int)
+	genre, err := h.service.GetGenreByID(r.Context(), genreID)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	filter := models.BookFilter{Conditions: []models.Condition{{Field: "genre", Op: models.OpEq, Values: []interface{}{genre.ID}}}}
+	h.writeOPDSBooks(w, r, opdsID+"genres:"+strconv.Itoa(genre.ID), genre.Name, opdsGenre(genre.ID), url.Values{}, filter)
+}
+
+// GetOPDSOpenSearch describes how to search the catalog by book name.
+func (h *Handler) GetOPDSOpenSearch(w http.ResponseWriter, r *http.Request) {
+	writeXML(w, r, opds.OpenSearchType, opds.OpenSearchDescription{
+		ShortName:   "Books",
+		Description: "Search books by name",
+		URL:         opds.URL{Type: opds.AcquisitionType, Template: opdsBooks + "?q={searchTerms}"},
+	})
+}
+
+// writeOPDSBooks writes a page of an acquisition feed. params are kept in
+// the pagination links, next to the cursor.
+func (h *Handler) writeOPDSBooks(w http.ResponseWriter, r *http.Request, id, title, path string, params url.Values, filter models.BookFilter) {
+	page := models.Page{Limit: models.DefaultPageLimit}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		c, err := models.DecodeCursor(cursor)
+		if err != nil {
+			_ = render.Render(w, r, ErrorRenderer(err))
+			return
+		}
+		page.Cursor = c
+	}
+	genres, err := h.service.GetAllGenres(r.Context())
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	books, err := h.service.GetAllBooks(r.Context(), filter, page)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	genreNames := make(map[int]string, len(genres.Genres))
+	for _, genre := range genres.Genres {
+		genreNames[genre.ID] = genre.Name
+	}
+
+	updated := h.now().UTC()
+	self := withParams(path, params)
+	if !page.Cursor.IsZero() {
+		self = withCursor(path, params, r.URL.Query().Get("cursor"))
+	}
+	feed := opds.Feed{
+		ID:      id,
+		Title:   title,
+		Updated: updated,
+		Links: []opds.Link{
+			{Rel: opds.RelSelf, Href: self, Type: opds.AcquisitionType},
+			{Rel: opds.RelStart, Href: opdsRoot, Type: opds.NavigationType},
+			{Rel: opds.RelUp, Href: opdsRoot, Type: opds.NavigationType},
+			{Rel: opds.RelFirst, Href: withParams(path, params), Type: opds.AcquisitionType},
+			{Rel: opds.RelSearch, Href: opdsOpenSearch, Type: opds.OpenSearchType},
+		},
+	}
+	if books.HasMore {
+		feed.Links = append(feed.Links, opds.Link{
+			Rel: opds.RelNext, Href: withCursor(path, params, books.NextCursor), Type: opds.AcquisitionType,
+		})
+	}
+	for _, book := range books.Books {
+		feed.Entries = append(feed.Entries, bookEntry(book, genreNames[book.Genre]))
+	}
+	writeXML(w, r, opds.AcquisitionType, feed)
+}
+
+func bookEntry(book models.Book, genre string) opds.Entry {
+	entry := opds.Entry{
+		ID:         opdsID + "books:" + strconv.Itoa(book.ID),
+		Title:      book.Name,
+		Updated:    book.UpdatedAt.UTC(),
+		Categories: []opds.Category{{Term: strconv.Itoa(book.Genre), Label: genre}},
+		Content:    opds.TextContent(bookSummary(book)),
+		// There is nothing to download or buy yet, so entries only point at
+		// the book in the JSON API rather than offering an acquisition link.
+		Links: []opds.Link{
+			{Rel: opds.RelAlternate, Href: bookPath(book.ID), Type: "application/json"},
+		},
+	}
+	for _, author := range book.Authors {
+		entry.Authors = append(entry.Authors, opds.Person{Name: author.Name})
+	}
+	return entry
+}
+
+func opdsGenre(id int) string {
+	return opdsRoot + "/genres/" + strconv.Itoa(id)
+}
+
+func withParams(path string, params url.Values) string {
+	if len(params) == 0 {
+		return path
+	}
+	return path + "?" + params.Encode()
+}
+
+func withCursor(path string, params url.Values, cursor string) string {
+	query := url.Values{"cursor": {cursor}}
+	for key, values := range params {
+		query[key] = values
+	}
+	return path + "?" + query.Encode()
+}
+
+// writeXML writes v as an XML document of the given content type.
+func writeXML(w http.ResponseWriter, r *http.Request, contentType string, v interface{}) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
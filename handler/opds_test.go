@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOPDS(t *testing.T) {
+	clock := WithClock(func() time.Time { return time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC) })
+
+	genres := &models.GenreList{Genres: []models.Genre{{ID: 1, Name: "adventure"}, {ID: 3, Name: "Fantasy"}}}
+	cursor := models.Cursor{ID: 7}.Encode()
+	type mockBehavior func(s *mock.MockDatabaseBooksManager)
+	tests := []struct {
+		name                 string
+		target               string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedResponseBody string
+	}{
+		{
+			name:   "Navigation",
+			target: "/opds",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/atom+xml;profile=opds-catalog;kind=navigation",
+			expectedResponseBody: `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>urn:bookstore:catalog</id>
+  <title>Books</title>
+  <updated>2021-03-01T12:00:00Z</updated>
+  <link rel="self" href="/opds" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link rel="start" href="/opds" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link rel="search" href="/opds/opensearch.xml" type="application/opensearchdescription+xml"></link>
+  <entry>
+    <id>urn:bookstore:books</id>
+    <title>All books</title>
+    <updated>2021-03-01T12:00:00Z</updated>
+    <content type="text">Every book in stock</content>
+    <link rel="subsection" href="/opds/books" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
+  </entry>
+  <entry>
+    <id>urn:bookstore:genres:1</id>
+    <title>adventure</title>
+    <updated>2021-03-01T12:00:00Z</updated>
+    <content type="text">Books in adventure</content>
+    <link rel="subsection" href="/opds/genres/1" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
+  </entry>
+  <entry>
+    <id>urn:bookstore:genres:3</id>
+    <title>Fantasy</title>
+    <updated>2021-03-01T12:00:00Z</updated>
+    <content type="text">Books in Fantasy</content>
+    <link rel="subsection" href="/opds/genres/3" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
+  </entry>
+</feed>`,
+		},
+		{
+			name:   "Acquisition with next page",
+			target: "/opds/books?q=hobbit&cursor=" + cursor,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+				r.EXPECT().GetAllBooks(gomock.Any(), models.BookFilter{Search: "hobbit"},
+					models.Page{Limit: models.DefaultPageLimit, Cursor: models.Cursor{ID: 7}}).
+					Return(&models.BookList{
+						Books: []models.Book{{ID: 5, Name: "The Hobbit", Genre: 3, Price: 9.5, Amount: 3,
+							UpdatedAt: time.Date(2021, 2, 1, 9, 30, 0, 0, time.UTC),
+							Authors:   []models.AuthorSummary{{ID: 1, Name: "J. R. R. Tolkien"}}}},
+						NextCursor: "next",
+						HasMore:    true,
+					}, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/atom+xml;profile=opds-catalog;kind=acquisition",
+			expectedResponseBody: `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>urn:bookstore:books</id>
+  <title>Search results for &#34;hobbit&#34;</title>
+  <updated>2021-03-01T12:00:00Z</updated>
+  <link rel="self" href="/opds/books?cursor=` + cursor + `&amp;q=hobbit" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
+  <link rel="start" href="/opds" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link rel="up" href="/opds" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link rel="first" href="/opds/books?q=hobbit" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
+  <link rel="search" href="/opds/opensearch.xml" type="application/opensearchdescription+xml"></link>
+  <link rel="next" href="/opds/books?cursor=next&amp;q=hobbit" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
+  <entry>
+    <id>urn:bookstore:books:5</id>
+    <title>The Hobbit</title>
+    <updated>2021-02-01T09:30:00Z</updated>
+    <author>
+      <name>J. R. R. Tolkien</name>
+    </author>
+    <category term="3" label="Fantasy"></category>
+    <content type="text">Price: 9.5. In stock: 3.</content>
+    <link rel="alternate" href="/books/5" type="application/json"></link>
+  </entry>
+</feed>`,
+		},
+		{
+			name:   "Genre",
+			target: "/opds/genres/1",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1, Name: "adventure"}, nil)
+				r.EXPECT().GetAllGenres(gomock.Any()).Return(genres, nil)
+				r.EXPECT().GetAllBooks(gomock.Any(),
+					models.BookFilter{Conditions: []models.Condition{{Field: "genre", Op: models.OpEq, Values: []interface{}{1}}}},
+					models.Page{Limit: models.DefaultPageLimit}).
+					Return(&models.BookList{Books: []models.Book{}}, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/atom+xml;profile=opds-catalog;kind=acquisition",
+			expectedResponseBody: `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>urn:bookstore:genres:1</id>
+  <title>adventure</title>
+  <updated>2021-03-01T12:00:00Z</updated>
+  <link rel="self" href="/opds/genres/1" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
+  <link rel="start" href="/opds" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link rel="up" href="/opds" type="application/atom+xml;profile=opds-catalog;kind=navigation"></link>
+  <link rel="first" href="/opds/genres/1" type="application/atom+xml;profile=opds-catalog;kind=acquisition"></link>
+  <link rel="search" href="/opds/opensearch.xml" type="application/opensearchdescription+xml"></link>
+</feed>`,
+		},
+		{
+			name:   "Unknown genre",
+			target: "/opds/genres/9",
+			mockBehavior: func(r *mock.MockDatabaseBooksManager) {
+				r.EXPECT().GetGenreByID(gomock.Any(), 9).Return(models.Genre{}, &db.NotFoundError{Message: "genre not found"})
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"genre not found\"}\n",
+		},
+		{
+			name:                 "Empty search",
+			target:               "/opds/books?q=",
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedContentType:  "application/json",
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"q has to have between 1 and 100 characters\"}\n",
+		},
+		{
+			name:                "OpenSearch description",
+			target:              "/opds/opensearch.xml",
+			mockBehavior:        func(r *mock.MockDatabaseBooksManager) {},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/opensearchdescription+xml",
+			expectedResponseBody: `<?xml version="1.0" encoding="UTF-8"?>
+<OpenSearchDescription xmlns="http://a9.com/-/spec/opensearch/1.1/">
+  <ShortName>Books</ShortName>
+  <Description>Search books by name</Description>
+  <Url type="application/atom+xml;profile=opds-catalog;kind=acquisition" template="/opds/books?q={searchTerms}"></Url>
+</OpenSearchDescription>`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			services := service.NewService(mockManager)
+			handler := NewHandler(services, clock)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.target, nil)
+			handler.InitRoutes().ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), test.expectedContentType)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/patch"
+	"github.com/go-chi/render"
+	"io/ioutil"
+	"mime"
+	"net/http"
+)
+
+// maxPatchSize bounds a PATCH body. A patch touches a single book, so
+// anything larger is a mistake or abuse.
+const maxPatchSize = 1 << 20
+
+var errPatchTooLarge = fmt.Errorf("patch can't be larger than %d bytes", maxPatchSize)
+
+// PatchBook updates only the fields present in the request body. Plain JSON
+// and application/merge-patch+json bodies are treated as a JSON Merge Patch,
+// application/json-patch+json bodies as a JSON Patch. Validation runs on the
+// merged book, so the result has to be a valid book as a whole.
+func (h *Handler) PatchBook(w http.ResponseWriter, r *http.Request) {
+	bookID := r.Context().Value(bookIDKey).(int)
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case patch.ContentTypeMergePatch, "application/json":
+		apply = patch.MergePatch
+	case patch.ContentTypeJSONPatch:
+		apply = patch.Apply
+	default:
+		_ = render.Render(w, r, UnsupportedMediaTypeErrorRenderer(
+			fmt.Errorf("content type has to be %s or %s", patch.ContentTypeMergePatch, patch.ContentTypeJSONPatch)))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		// MaxBytesReader fails only once the limit has been read.
+		if len(body) >= maxPatchSize {
+			_ = render.Render(w, r, RequestEntityTooLargeErrorRenderer(errPatchTooLarge))
+		} else {
+			_ = render.Render(w, r, ErrorRenderer(err))
+		}
+		return
+	}
+	header := r.Header.Get("If-Match")
+	if header == "" && h.requireIfMatch {
+		_ = render.Render(w, r, RepositoryErrorRenderer(errPreconditionRequired))
+		return
+	}
+	book, err := h.service.GetBookByID(r.Context(), bookID)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	if header != "" && !matchesETag(header, book.Version) {
+		_ = render.Render(w, r, RepositoryErrorRenderer(db.ErrVersionMismatch))
+		return
+	}
+	doc, err := json.Marshal(book)
+	if err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+		return
+	}
+	patched, err := apply(doc, body)
+	if err != nil {
+		if errors.Is(err, patch.ErrMalformed) {
+			_ = render.Render(w, r, ErrorRenderer(err))
+		} else {
+			_ = render.Render(w, r, UnprocessableEntityErrorRenderer(err))
+		}
+		return
+	}
+
+	bookData := models.Book{}
+	if err := json.Unmarshal(patched, &bookData); err != nil {
+		_ = render.Render(w, r, UnprocessableEntityErrorRenderer(err))
+		return
+	}
+	// The ID comes from the URL and can't be patched. The update is tied to
+	// the version the patch was applied to, so concurrent edits aren't lost.
+	bookData.ID = bookID
+	bookData.Version = book.Version
+	// An update without an ISBN keeps the stored one, so a patch removing it
+	// has to say so.
+	bookData.ClearISBN = book.ISBN != "" && bookData.ISBN == ""
+	if err := bookData.Bind(r); err != nil {
+		_ = render.Render(w, r, ErrorRenderer(err))
+		return
+	}
+	newBookID, err := h.service.UpdateBookByID(r.Context(), bookID, bookData)
+	if err != nil {
+		_ = render.Render(w, r, RepositoryErrorRenderer(err))
+		return
+	}
+	setWrittenETag(w, bookData.Version)
+	if err := render.Render(w, r, models.CreateBookResponse{BookID: newBookID}); err != nil {
+		_ = render.Render(w, r, ServerErrorRenderer(err))
+	}
+}
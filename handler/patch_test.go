@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	mock "github.com/GlobantObrikosina/golang-rest-api/service/mocks"
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPatchBook(t *testing.T) {
+	current := models.Book{
+		ID:      1,
+		Name:    "Book1",
+		Genre:   1,
+		Price:   12.5,
+		Amount:  3,
+		Authors: []models.AuthorSummary{{ID: 7, Name: "Jules Verne"}},
+	}
+	type mockBehavior func(s *mock.MockDatabaseBooksManager, id int)
+	tests := []struct {
+		name                 string
+		inputId              int
+		contentType          string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:        "Merge patch amount",
+			inputId:     1,
+			contentType: "application/merge-patch+json",
+			inputBody:   `{"amount": 10}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				patched := current
+				patched.Amount = 10
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(current, nil)
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), id, patched).Return(id, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":1}\n",
+		},
+		{
+			name:        "JSON patch price",
+			inputId:     1,
+			contentType: "application/json-patch+json",
+			inputBody:   `[{"op": "test", "path": "/price", "value": 12.5}, {"op": "replace", "path": "/price", "value": 9.99}]`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				patched := current
+				patched.Price = 9.99
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(current, nil)
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), id, patched).Return(id, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":1}\n",
+		},
+		{
+			name:        "Merged book invalid",
+			inputId:     1,
+			contentType: "application/merge-patch+json",
+			inputBody:   `{"name": null, "amount": -1}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(current, nil)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"name is a required field; amount can't be less than 0\"}\n",
+		},
+		{
+			name:        "Wrong type",
+			inputId:     1,
+			contentType: "application/merge-patch+json",
+			inputBody:   `{"amount": "ten"}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(current, nil)
+			},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: "{\"status_text\":\"Unprocessable entity\",\"message\":\"json: cannot unmarshal string into Go struct field Book.amount of type int\"}\n",
+		},
+		{
+			name:        "Malformed patch",
+			inputId:     1,
+			contentType: "application/merge-patch+json",
+			inputBody:   `{"amount":`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(current, nil)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "{\"status_text\":\"Bad request\",\"message\":\"malformed patch: unexpected EOF\"}\n",
+		},
+		{
+			name:        "Failed test operation",
+			inputId:     1,
+			contentType: "application/json-patch+json",
+			inputBody:   `[{"op": "test", "path": "/amount", "value": 0}]`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(current, nil)
+			},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: "{\"status_text\":\"Unprocessable entity\",\"message\":\"operation 0: test failed at \\\"/amount\\\"\"}\n",
+		},
+		{
+			name:        "Merge patch removing ISBN",
+			inputId:     1,
+			contentType: "application/merge-patch+json",
+			inputBody:   `{"isbn": null}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				stored := current
+				stored.ISBN = "9780261103344"
+				patched := current
+				patched.ClearISBN = true
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(stored, nil)
+				r.EXPECT().GetGenreByID(gomock.Any(), 1).Return(models.Genre{ID: 1}, nil)
+				r.EXPECT().UpdateBookByID(gomock.Any(), id, patched).Return(id, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: "{\"id\":1}\n",
+		},
+		{
+			name:                 "Unsupported content type",
+			inputId:              1,
+			contentType:          "text/plain",
+			inputBody:            `amount=1`,
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, id int) {},
+			expectedStatusCode:   http.StatusUnsupportedMediaType,
+			expectedResponseBody: "{\"status_text\":\"Unsupported media type\",\"message\":\"content type has to be application/merge-patch+json or application/json-patch+json\"}\n",
+		},
+		{
+			name:                 "Patch too large",
+			inputId:              1,
+			contentType:          "application/merge-patch+json",
+			inputBody:            `{"description": "` + strings.Repeat("a", maxPatchSize) + `"}`,
+			mockBehavior:         func(r *mock.MockDatabaseBooksManager, id int) {},
+			expectedStatusCode:   http.StatusRequestEntityTooLarge,
+			expectedResponseBody: "{\"status_text\":\"Request entity too large\",\"message\":\"patch can't be larger than 1048576 bytes\"}\n",
+		},
+		{
+			name:        "Book not found",
+			inputId:     5,
+			contentType: "application/json",
+			inputBody:   `{"amount": 10}`,
+			mockBehavior: func(r *mock.MockDatabaseBooksManager, id int) {
+				r.EXPECT().GetBookByID(gomock.Any(), id).Return(models.Book{}, db.ErrNoMatch)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "{\"status_text\":\"Not found\",\"message\":\"no matching record\"}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock.NewMockDatabaseBooksManager(c)
+			test.mockBehavior(mockManager, test.inputId)
+
+			services := service.NewService(mockManager)
+			handler := Handler{service: services}
+
+			r := chi.NewRouter()
+			r.Route("/{bookID}", func(router chi.Router) {
+				router.Use(handler.BookContext)
+				router.Patch("/", handler.PatchBook)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PATCH", fmt.Sprintf("/%v", test.inputId), bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Content-Type", test.contentType)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/catalog"
+	"github.com/GlobantObrikosina/golang-rest-api/config"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"github.com/GlobantObrikosina/golang-rest-api/service"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// runImport implements "import [-format csv|ndjson] FILE", which does what
+// POST /books/import does for a local file, or standard input when FILE is
+// "-". It prints a line per row and returns the exit status: 1 if the import
+// failed or rejected any row.
+func runImport(cfg config.Config, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "catalog format, csv or ndjson (default: from the file extension)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: golang-rest-api import [-format csv|ndjson] FILE")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		input = file
+	}
+	reader, err := catalog.NewReader(input, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	database, err := openDatabase(ctx, cfg.Database)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.Close()
+
+	report, err := service.NewService(database).ImportBooks(ctx, reader)
+	if report != nil {
+		printImportReport(os.Stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if report.Rejected > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printImportReport(w io.Writer, report *models.ImportReport) {
+	for _, row := range report.Rows {
+		switch row.Status {
+		case models.ImportRejected:
+			fmt.Fprintf(w, "line %d: %s %q: %s\n", row.Line, row.Status, row.Name, row.Message)
+		default:
+			fmt.Fprintf(w, "line %d: %s %q (id %d)\n", row.Line, row.Status, row.Name, row.ID)
+		}
+	}
+	fmt.Fprintf(w, "%d created, %d updated, %d rejected\n", report.Created, report.Updated, report.Rejected)
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"flag"
+	"github.com/GlobantObrikosina/golang-rest-api/config"
 	"github.com/GlobantObrikosina/golang-rest-api/db"
 	"github.com/GlobantObrikosina/golang-rest-api/handler"
 	"github.com/GlobantObrikosina/golang-rest-api/service"
@@ -15,42 +17,151 @@ import (
 	"time"
 )
 
+// logLevel is the least severe kind of message main logs.
+var logLevel = config.LevelInfo
+
+// infof logs an informational message unless the log level hides it.
+func infof(format string, args ...interface{}) {
+	if logLevel.Enables(config.LevelInfo) {
+		log.Printf(format, args...)
+	}
+}
+
+// warnf logs a warning unless the log level hides it.
+func warnf(format string, args ...interface{}) {
+	if logLevel.Enables(config.LevelWarn) {
+		log.Printf(format, args...)
+	}
+}
+
 func main() {
-	addr := ":8080"
-	listener, err := net.Listen("tcp", addr)
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Fatalf("Error occurred: %s", err.Error())
+		log.Fatalf("Could not load configuration: %v", err)
+	}
+	logLevel = cfg.Log.Level
+	if len(args) > 0 {
+		switch args[0] {
+		case "import":
+			os.Exit(runImport(cfg, args[1:]))
+		case "migrate":
+			os.Exit(runMigrate(cfg, args[1:]))
+		default:
+			log.Fatalf("Unknown command %q: has to be import or migrate", args[0])
+		}
 	}
-	dbUser, dbPassword, dbName :=
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB")
 
-	database := db.NewDatabase(dbUser, dbPassword, dbName)
+	listener, err := net.Listen("tcp", cfg.Server.Addr)
+	if err != nil {
+		log.Fatalf("Error occurred: %s", err.Error())
+	}
+	// ctx is done on the first signal, which stops connecting as well as
+	// serving.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	database, err := openDatabase(ctx, cfg.Database)
+	if err != nil {
+		log.Fatalf("Could not set up database: %v", err)
+	}
+	infof("Connected to the %s database", cfg.Database.Backend)
+	defer database.Close()
+	if cfg.Database.AutoMigrate {
+		if err := migrateOnBoot(ctx, database); err != nil {
+			log.Fatalf("Could not migrate database: %s", err.Error())
+		}
+	}
+	monitor := db.NewHealthMonitor(database, warnf)
+	go monitor.Run(ctx, cfg.Database.HealthCheckInterval)
 	services := service.NewService(database)
-	httpHandler := handler.NewHandler(services)
+	handlerOptions := []handler.Option{handler.WithHealthMonitor(monitor)}
+	if cfg.Server.RequireIfMatch {
+		handlerOptions = append(handlerOptions, handler.WithRequiredIfMatch())
+	}
+	if logLevel.Enables(config.LevelDebug) {
+		handlerOptions = append(handlerOptions, handler.WithRequestLog())
+	}
+	httpHandler := handler.NewHandler(services, handlerOptions...)
 
-	defer database.Close()
-	server := &http.Server{Handler: httpHandler.InitRoutes()}
+	// Requests inherit baseCtx, so cancelling it aborts their queries.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	server := &http.Server{
+		Handler:           httpHandler.InitRoutes(),
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	tls := cfg.Server.TLS
 	go func() {
-		err := server.Serve(listener)
-		if err != nil {
-			log.Printf("Server some how didn't start")
+		var err error
+		if tls.Enabled() {
+			err = server.ServeTLS(listener, tls.CertFile, tls.KeyFile)
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Server some how didn't start: %v", err)
 		}
 	}()
-	defer Stop(server)
-	log.Printf("Started server on %s", addr)
-	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
-	log.Println(fmt.Sprint(<-ch))
-	log.Println("Stopping API server.")
+	defer Stop(server, cancelRequests, cfg.Server.ShutdownTimeout)
+	infof("Started server on %s", listener.Addr())
+	<-ctx.Done()
+	infof("Stopping API server.")
+}
+
+// openDatabase connects to the database of the configured backend. Postgres
+// is waited for while it can't be reached, until the connect timeout or ctx
+// is done.
+func openDatabase(ctx context.Context, cfg config.Database) (db.DatabaseBooksManager, error) {
+	switch cfg.Backend {
+	case config.BackendMemory:
+		return db.NewMemoryDatabase(), nil
+	case config.BackendSQLite:
+		database, err := db.NewSQLiteDatabase(cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		database.QueryTimeout = cfg.QueryTimeout
+		return database, nil
+	}
+
+	return db.NewDatabase(ctx, cfg.DSN(), db.Options{
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnectTimeout:  cfg.ConnectTimeout,
+		QueryTimeout:    cfg.QueryTimeout,
+		Logf:            warnf,
+	})
+}
+
+// migrateOnBoot applies the pending migrations of database, if it has a
+// schema.
+func migrateOnBoot(ctx context.Context, database db.DatabaseBooksManager) error {
+	if _, ok := database.(*db.MemoryDatabase); ok {
+		return nil
+	}
+	runner, err := newMigrationRunner(database)
+	if err != nil {
+		return err
+	}
+	applied, err := runner.Up(ctx)
+	for _, migration := range applied {
+		infof("Applied migration %d_%s", migration.Version, migration.Name)
+	}
+	return err
 }
 
-func Stop(server *http.Server) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func Stop(server *http.Server, cancelRequests context.CancelFunc, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	defer cancelRequests()
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Could not shut down server correctly: %v\n", err)
+		cancelRequests()
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/config"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/migrations"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runMigrate implements "migrate up|down|status", which applies, reverts or
+// lists the migrations of the configured database. It returns the exit
+// status.
+func runMigrate(cfg config.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations down reverts")
+	all := flags.Bool("all", false, "make down revert every migration")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: golang-rest-api migrate up|status")
+		fmt.Fprintln(flags.Output(), "       golang-rest-api migrate down [-steps N | -all]")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return 2
+	}
+	command := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || (command != "down" && (*all || *steps != 1)) {
+		flags.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	database, err := openDatabase(ctx, cfg.Database)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.Close()
+	runner, err := newMigrationRunner(database)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch command {
+	case "up":
+		applied, err := runner.Up(ctx)
+		printMigrations(os.Stdout, "applied", applied)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "down":
+		if *all {
+			*steps = 0
+		}
+		reverted, err := runner.Down(ctx, *steps)
+		printMigrations(os.Stdout, "reverted", reverted)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "status":
+		status, err := runner.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		printMigrationStatus(os.Stdout, status)
+	default:
+		flags.Usage()
+		return 2
+	}
+	return 0
+}
+
+// newMigrationRunner returns a runner for the schema of database.
+func newMigrationRunner(database db.DatabaseBooksManager) (*migrations.Runner, error) {
+	switch database := database.(type) {
+	case db.Database:
+		return migrations.NewRunner(database.Conn, migrations.Postgres)
+	case db.SQLiteDatabase:
+		return migrations.NewRunner(database.Conn, migrations.SQLite)
+	default:
+		return nil, fmt.Errorf("the %T backend has no schema to migrate", database)
+	}
+}
+
+func printMigrations(w io.Writer, action string, list []migrations.Migration) {
+	for _, migration := range list {
+		fmt.Fprintf(w, "%s %d_%s\n", action, migration.Version, migration.Name)
+	}
+	if len(list) == 0 {
+		fmt.Fprintf(w, "nothing %s\n", action)
+	}
+}
+
+func printMigrationStatus(w io.Writer, status migrations.Status) {
+	fmt.Fprintf(w, "version %d", status.Version)
+	if status.Dirty {
+		fmt.Fprint(w, " (dirty)")
+	}
+	fmt.Fprintln(w)
+	for _, migration := range status.Migrations {
+		state := "applied"
+		if migration.Version > status.Version {
+			state = "pending"
+		}
+		fmt.Fprintf(w, "%-8s %d_%s\n", state, migration.Version, migration.Name)
+	}
+}
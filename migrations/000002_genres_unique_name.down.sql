@@ -0,0 +1 @@
+ALTER TABLE genres DROP CONSTRAINT IF EXISTS genres_name_key;
@@ -0,0 +1,3 @@
+ALTER TABLE genres ADD CONSTRAINT genres_name_key UNIQUE (name);
+
+SELECT setval('genres_id_seq', (SELECT MAX(id) FROM genres));
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS books_name_trgm_idx;
+DROP INDEX IF EXISTS books_search_idx;
+ALTER TABLE books DROP COLUMN IF EXISTS search;
@@ -0,0 +1,88 @@
+// Package migrations holds the database schema as numbered SQL files and
+// applies them. The files in this directory are for Postgres, the ones in
+// sqlite/ take the same steps for SQLite.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Dialect is the database a set of migrations is written for.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+//go:embed *.sql sqlite/*.sql
+var files embed.FS
+
+// Migration is one step of the schema, with the scripts applying and
+// reverting it.
+type Migration struct {
+	Version int
+	// Name is the file name without the version and the direction, e.g.
+	// "add_books_isbn".
+	Name string
+	Up   string
+	Down string
+}
+
+// Load returns the migrations of dialect in version order.
+func Load(dialect Dialect) ([]Migration, error) {
+	dir := "."
+	switch dialect {
+	case Postgres:
+	case SQLite:
+		dir = "sqlite"
+	default:
+		return nil, fmt.Errorf("unknown dialect %q", dialect)
+	}
+	paths, err := fs.Glob(files, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, file := range paths {
+		var version int
+		base := path.Base(file)
+		if _, err := fmt.Sscanf(base, "%d_", &version); err != nil {
+			return nil, fmt.Errorf("%s: no version: %w", file, err)
+		}
+		script, err := fs.ReadFile(files, file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.SplitN(strings.TrimSuffix(base, ".sql"), "_", 2)[1]
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version}
+			byVersion[version] = migration
+		}
+		switch {
+		case strings.HasSuffix(name, ".up"):
+			migration.Name = strings.TrimSuffix(name, ".up")
+			migration.Up = string(script)
+		case strings.HasSuffix(name, ".down"):
+			migration.Down = string(script)
+		default:
+			return nil, fmt.Errorf("%s: has to end in .up.sql or .down.sql", file)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d of %s needs both an up and a down script", migration.Version, dialect)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
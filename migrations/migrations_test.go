@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	postgres, err := Load(Postgres)
+	assert.NoError(t, err)
+	sqlite, err := Load(SQLite)
+	assert.NoError(t, err)
+
+	// Both dialects take the same steps, so a version means the same schema.
+	if assert.Len(t, sqlite, len(postgres)) {
+		for i := range postgres {
+			assert.Equal(t, postgres[i].Version, sqlite[i].Version)
+			assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+		}
+	}
+	assert.Equal(t, 1, postgres[0].Version)
+	assert.Equal(t, "create_items_table", postgres[0].Name)
+
+	_, err = Load("mysql")
+	assert.EqualError(t, err, `unknown dialect "mysql"`)
+}
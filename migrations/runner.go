@@ -0,0 +1,219 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// lockID is the Postgres advisory lock held while migrating, so replicas
+// starting together apply every migration once.
+const lockID = 7242031901
+
+// Runner applies and reverts migrations, recording the schema version in a
+// schema_migrations table laid out like the one of the migrate tool, so
+// databases it migrated carry on from where it stopped.
+//
+// Each migration runs in a transaction together with the version update.
+// On Postgres an advisory lock keeps runners of other processes waiting.
+// SQLite has no such lock, so a runner only checks in its transaction that
+// no one else moved the version meanwhile.
+type Runner struct {
+	conn       *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// Status is where the schema of a database stands.
+type Status struct {
+	// Version is the last migration applied, 0 if none.
+	Version int
+	// Dirty is set when a migration failed halfway under the migrate tool,
+	// which has to be fixed by hand.
+	Dirty bool
+	// Migrations lists every known migration, applied or not.
+	Migrations []Migration
+}
+
+// Pending returns the migrations not applied yet.
+func (s Status) Pending() []Migration {
+	var pending []Migration
+	for _, migration := range s.Migrations {
+		if migration.Version > s.Version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+func NewRunner(conn *sql.DB, dialect Dialect) (*Runner, error) {
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{conn: conn, dialect: dialect, migrations: migrations}, nil
+}
+
+// Status reports the current version and the known migrations.
+func (r *Runner) Status(ctx context.Context) (Status, error) {
+	status := Status{Migrations: r.migrations}
+	conn, err := r.conn.Conn(ctx)
+	if err != nil {
+		return status, err
+	}
+	defer conn.Close()
+	if err := r.prepare(ctx, conn); err != nil {
+		return status, err
+	}
+	status.Version, status.Dirty, err = readVersion(ctx, conn)
+	return status, err
+}
+
+// Up applies every pending migration in order and returns them. It stops at
+// the first one that fails, keeping those before it.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := r.locked(ctx, func(conn *sql.Conn, version int) error {
+		for _, migration := range r.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			if err := r.apply(ctx, conn, migration.Up, version, migration.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			version = migration.Version
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, or all of them if steps
+// isn't positive, and returns them in the order they were reverted.
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := r.locked(ctx, func(conn *sql.Conn, version int) error {
+		for i := len(r.migrations) - 1; i >= 0; i-- {
+			if steps > 0 && len(reverted) == steps {
+				break
+			}
+			migration := r.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+			previous := 0
+			if i > 0 {
+				previous = r.migrations[i-1].Version
+			}
+			if err := r.apply(ctx, conn, migration.Down, version, previous); err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			version = previous
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// locked calls fn on a connection holding the migration lock, with the
+// current schema version.
+func (r *Runner) locked(ctx context.Context, fn func(conn *sql.Conn, version int) error) error {
+	conn, err := r.conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if r.dialect == Postgres {
+		// Advisory locks belong to the session, so everything has to run on
+		// this connection.
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+			return fmt.Errorf("could not lock migrations: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+	}
+	if err := r.prepare(ctx, conn); err != nil {
+		return err
+	}
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d: a migration failed halfway and has to be fixed by hand", version)
+	}
+	return fn(conn, version)
+}
+
+// prepare creates schema_migrations if needed. SQLite databases migrated
+// before it existed kept their version in user_version, which is carried
+// over.
+func (r *Runner) prepare(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`)
+	if err != nil {
+		return err
+	}
+	if r.dialect != SQLite {
+		return nil
+	}
+	var recorded bool
+	err = conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations)`).Scan(&recorded)
+	if err != nil || recorded {
+		return err
+	}
+	var userVersion int
+	if err := conn.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&userVersion); err != nil {
+		return err
+	}
+	if userVersion == 0 {
+		return nil
+	}
+	_, err = conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, userVersion)
+	return err
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func readVersion(ctx context.Context, conn queryRower) (version int, dirty bool, err error) {
+	err = conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// apply runs script and moves the version from from to to in one
+// transaction.
+func (r *Runner) apply(ctx context.Context, conn *sql.Conn, script string, from int, to int) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, _, err := readVersion(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if current != from {
+		return fmt.Errorf("schema moved from version %d to %d meanwhile", from, current)
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if to > 0 {
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, to)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
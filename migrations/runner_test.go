@@ -0,0 +1,179 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "books.db")+"?_pragma=foreign_keys(1)&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("Could not open database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func versions(list []Migration) []int {
+	result := []int{}
+	for _, migration := range list {
+		result = append(result, migration.Version)
+	}
+	return result
+}
+
+func TestRunnerSQLite(t *testing.T) {
+	ctx := context.Background()
+	conn := openSQLite(t)
+	runner, err := NewRunner(conn, SQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	status, err := runner.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, status.Version)
+	assert.Len(t, status.Pending(), 7)
+
+	applied, err := runner.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7}, versions(applied))
+	var genres int
+	assert.NoError(t, conn.QueryRow(`SELECT COUNT(*) FROM genres`).Scan(&genres))
+	assert.Equal(t, 3, genres)
+
+	applied, err = runner.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := runner.Down(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7, 6}, versions(reverted))
+	status, err = runner.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, status.Version)
+	assert.Equal(t, []int{6, 7}, versions(status.Pending()))
+
+	reverted, err = runner.Down(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5, 4, 3, 2, 1}, versions(reverted))
+	var tables int
+	assert.NoError(t, conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'books'`).Scan(&tables))
+	assert.Equal(t, 0, tables)
+
+	applied, err = runner.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 7)
+}
+
+func TestRunnerSQLiteUserVersion(t *testing.T) {
+	ctx := context.Background()
+	conn := openSQLite(t)
+	migrations, err := Load(SQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// A database migrated before schema_migrations existed.
+	for _, migration := range migrations[:3] {
+		_, err := conn.Exec(migration.Up)
+		assert.NoError(t, err)
+	}
+	_, err = conn.Exec(`PRAGMA user_version = 3`)
+	assert.NoError(t, err)
+
+	runner, err := NewRunner(conn, SQLite)
+	if !assert.NoError(t, err) {
+		return
+	}
+	applied, err := runner.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{4, 5, 6, 7}, versions(applied))
+}
+
+func TestRunnerPostgres(t *testing.T) {
+	tests := []struct {
+		name             string
+		mockBehavior     func(mock sqlmock.Sqlmock)
+		expectedVersions []int
+		expectedError    string
+	}{
+		{
+			name: "Up to date",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WithArgs(lockID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(7, false))
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WithArgs(lockID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedVersions: []int{},
+		},
+		{
+			name: "One pending",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WithArgs(lockID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(6, false))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(6, false))
+				mock.ExpectExec(`ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`DELETE FROM schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WithArgs(lockID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedVersions: []int{7},
+		},
+		{
+			name: "Dirty",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WithArgs(lockID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(5, true))
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WithArgs(lockID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedVersions: []int{},
+			expectedError:    "database is dirty at version 5: a migration failed halfway and has to be fixed by hand",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Could not mock database: %v", err)
+			}
+			defer conn.Close()
+			test.mockBehavior(mock)
+
+			runner, err := NewRunner(conn, Postgres)
+			if !assert.NoError(t, err) {
+				return
+			}
+			applied, err := runner.Up(context.Background())
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedVersions, versions(applied))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
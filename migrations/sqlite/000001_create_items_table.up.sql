@@ -0,0 +1,18 @@
+-- AUTOINCREMENT keeps ids of deleted rows from being reused, like Postgres
+-- sequences.
+CREATE TABLE IF NOT EXISTS genres (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL
+);
+
+INSERT INTO genres VALUES (1, 'adventure');
+INSERT INTO genres VALUES (2, 'classics');
+INSERT INTO genres VALUES (3, 'fantasy');
+
+CREATE TABLE IF NOT EXISTS books (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    genre INT NOT NULL REFERENCES genres(id),
+    price REAL NOT NULL,
+    amount INT NOT NULL
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS genres_name_key;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS genres_name_key ON genres (name);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS books_search_update;
+DROP TRIGGER IF EXISTS books_search_delete;
+DROP TRIGGER IF EXISTS books_search_insert;
+DROP TABLE IF EXISTS books_search;
@@ -0,0 +1,18 @@
+-- SQLite has no tsvector, so book names are indexed in an FTS5 table kept
+-- in sync by triggers.
+CREATE VIRTUAL TABLE IF NOT EXISTS books_search USING fts5(name, content='books', content_rowid='id');
+
+CREATE TRIGGER IF NOT EXISTS books_search_insert AFTER INSERT ON books BEGIN
+    INSERT INTO books_search (rowid, name) VALUES (new.id, new.name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_search_delete AFTER DELETE ON books BEGIN
+    INSERT INTO books_search (books_search, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_search_update AFTER UPDATE OF name ON books BEGIN
+    INSERT INTO books_search (books_search, rowid, name) VALUES ('delete', old.id, old.name);
+    INSERT INTO books_search (rowid, name) VALUES (new.id, new.name);
+END;
+
+INSERT INTO books_search (books_search) VALUES ('rebuild');
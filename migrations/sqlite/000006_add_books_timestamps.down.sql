@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS books_created_at_idx;
+
+ALTER TABLE books DROP COLUMN updated_at;
+ALTER TABLE books DROP COLUMN created_at;
@@ -0,0 +1,11 @@
+-- Timestamps are stored as fixed width UTC text, so that they sort the same
+-- as the times they stand for. ALTER TABLE only takes constant defaults, so
+-- existing books are stamped afterwards.
+ALTER TABLE books ADD COLUMN created_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00.000000000+00:00';
+ALTER TABLE books ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00.000000000+00:00';
+
+UPDATE books SET
+    created_at = strftime('%Y-%m-%d %H:%M:%S.000000000+00:00', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%S.000000000+00:00', 'now');
+
+CREATE INDEX IF NOT EXISTS books_created_at_idx ON books (created_at DESC, id DESC);
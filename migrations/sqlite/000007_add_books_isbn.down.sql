@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS books_isbn_key;
+
+ALTER TABLE books DROP COLUMN isbn;
@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN isbn CHAR(13);
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_isbn_key ON books (isbn);
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"net/http"
+)
+
+type Author struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type AuthorList struct {
+	Authors []Author `json:"authors"`
+}
+
+// AuthorSummary is how an author is embedded into a book. Clients only need
+// to send the ID when assigning authors to a book.
+type AuthorSummary struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+func (i *Author) Bind(r *http.Request) error {
+	return Validate(i)
+}
+
+func (*Author) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (*AuthorList) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+type CreateAuthorResponse struct {
+	AuthorID int `json:"id"`
+}
+
+func (CreateAuthorResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
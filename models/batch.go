@@ -0,0 +1,75 @@
+package models
+
+import (
+	"net/http"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+
+	// MaxBatchSize is the most operations a single batch can hold.
+	MaxBatchSize = 1000
+)
+
+// BatchOperation is one step of a batch request. Book is required to create
+// or update, ID to update or delete. A non-zero Version makes an update or
+// delete conditional, like If-Match does for single requests.
+type BatchOperation struct {
+	Op      string `json:"op" binding:"required,oneof=create update delete"`
+	ID      int    `json:"id" binding:"min=0"`
+	Version int    `json:"version,omitempty" binding:"min=0"`
+	Book    *Book  `json:"book,omitempty"`
+}
+
+// Bind validates the operation and, through Book.Bind, the book it carries.
+func (o *BatchOperation) Bind(r *http.Request) error {
+	if err := Validate(o); err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	if o.Op != BatchCreate && o.ID == 0 {
+		errs = append(errs, FieldError{Field: "id", Message: "id is a required field"})
+	}
+	if o.Op == BatchDelete {
+		if len(errs) != 0 {
+			return errs
+		}
+		return nil
+	}
+	if o.Book == nil {
+		return append(errs, FieldError{Field: "book", Message: "book is a required field"})
+	}
+	if err := o.Book.Bind(r); err != nil {
+		bookErrs, ok := err.(ValidationErrors)
+		if !ok {
+			return err
+		}
+		errs = append(errs, bookErrs...)
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// BatchResult is the outcome of one operation of a batch. Status is the
+// HTTP status the operation would have had as a single request.
+type BatchResult struct {
+	Op      string       `json:"op"`
+	ID      int          `json:"id,omitempty"`
+	Status  int          `json:"status"`
+	Message string       `json:"message,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+	// Err is why the operation failed, nil if it succeeded or wasn't run.
+	Err error `json:"-"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+func (*BatchResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
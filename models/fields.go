@@ -0,0 +1,93 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Fields is a sparse fieldset: the JSON fields of a resource a client asked
+// for, in the order the resource declares them.
+type Fields []string
+
+// ParseFields reads a fields parameter like "id,name,price" and checks every
+// name against the json tags of v, which has to be a struct or a pointer to
+// one. An empty string means every field and gives nil Fields.
+func ParseFields(value string, v interface{}) (Fields, error) {
+	if value == "" {
+		return nil, nil
+	}
+	names := jsonFieldNames(reflect.TypeOf(v))
+	requested := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if !contains(names, name) {
+			return nil, ValidationErrors{{Field: "fields", Message: fmt.Sprintf("fields can't contain %q", name)}}
+		}
+		requested[name] = true
+	}
+	var fields Fields
+	for _, name := range names {
+		if requested[name] {
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonFieldNames(t reflect.Type) []string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("json") == "-" {
+			continue
+		}
+		names = append(names, fieldName(field))
+	}
+	return names
+}
+
+// Select encodes v as JSON keeping only the chosen fields. Fields that v
+// leaves out, like empty omitempty ones, stay out. Nil Fields keep
+// everything.
+func (f Fields) Select(v interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil || f == nil {
+		return data, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, name := range f {
+		value, ok := object[name]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
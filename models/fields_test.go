@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFields(t *testing.T) {
+	book := Book{ID: 1, Name: "hello", Genre: 2, Price: 4.5, Amount: 3, Available: true, Version: 7}
+	tests := []struct {
+		name          string
+		input         string
+		expectedJSON  string
+		expectedError string
+	}{
+		{
+			name:         "All fields",
+			input:        "",
+			expectedJSON: `{"id":1,"name":"hello","genre":2,"price":4.5,"amount":3,"available":true,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:         "Declaration order",
+			input:        "price,id",
+			expectedJSON: `{"id":1,"price":4.5}`,
+		},
+		{
+			name:         "Empty omitempty field",
+			input:        "name,authors",
+			expectedJSON: `{"name":"hello"}`,
+		},
+		{
+			name:          "Hidden field",
+			input:         "id,Version",
+			expectedError: `fields can't contain "Version"`,
+		},
+		{
+			name:          "Unknown field",
+			input:         "id,publisher",
+			expectedError: `fields can't contain "publisher"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := ParseFields(test.input, &Book{})
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			data, err := fields.Select(book)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedJSON, string(data))
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Operator compares a book field with the values of a Condition.
+type Operator string
+
+const (
+	OpEq       Operator = "eq"
+	OpGt       Operator = "gt"
+	OpGte      Operator = "gte"
+	OpLt       Operator = "lt"
+	OpLte      Operator = "lte"
+	OpContains Operator = "contains"
+)
+
+// Availability selects books by whether they are in stock.
+type Availability string
+
+const (
+	AvailabilityInStock    Availability = "in_stock"
+	AvailabilityOutOfStock Availability = "out_of_stock"
+	AvailabilityAll        Availability = "all"
+)
+
+// Condition narrows a book list down to books whose Field compares to
+// Values with Op. An OpEq condition with several values matches any of them.
+// Values are already converted to the field's type.
+type Condition struct {
+	Field  string
+	Op     Operator
+	Values []interface{}
+}
+
+// BookFilter is a parsed set of query parameters for listing books. Every
+// condition has to match.
+type BookFilter struct {
+	Conditions []Condition
+	// Search is free text matched against book names, empty if not searching.
+	Search string
+	// Availability defaults to AvailabilityInStock when empty.
+	Availability Availability
+}
+
+type filterField struct {
+	parse func(string) (interface{}, bool)
+	// kind describes what parse accepts, for error messages.
+	kind string
+	ops  []Operator
+	// list fields take comma separated values, e.g. genre=1,3.
+	list bool
+}
+
+var (
+	comparisonOps = []Operator{OpEq, OpGt, OpGte, OpLt, OpLte}
+
+	bookFilterFields = map[string]filterField{
+		"name":   {parse: parseText, kind: "non-empty text", ops: []Operator{OpEq, OpContains}},
+		"isbn":   {parse: parseISBN, kind: "a valid ISBN-10 or ISBN-13", ops: []Operator{OpEq}, list: true},
+		"genre":  {parse: parseID, kind: "a positive integer", ops: []Operator{OpEq}, list: true},
+		"author": {parse: parseID, kind: "a positive integer", ops: []Operator{OpEq}, list: true},
+		"price":  {parse: parseNumber, kind: "a number", ops: comparisonOps, list: true},
+		"amount": {parse: parseInteger, kind: "an integer", ops: comparisonOps, list: true},
+	}
+
+	filterKey = regexp.MustCompile(`^([a-z_]+)(?:\[([a-z]+)\])?$`)
+)
+
+// ParseBookFilter reads filter conditions like price[gte]=10 or genre=1,3
+// and the q and availability parameters from a query string. A key without an
+// operator compares for equality. All invalid keys and values are reported
+// at once.
+func ParseBookFilter(query url.Values) (BookFilter, error) {
+	filter := BookFilter{}
+	var errs ValidationErrors
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	// Sorted keys keep the generated SQL the same for the same filter.
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "q" {
+			q := strings.TrimSpace(query.Get(key))
+			if q == "" || utf8.RuneCountInString(q) > 100 {
+				errs = append(errs, FieldError{Field: key, Message: "q has to have between 1 and 100 characters"})
+				continue
+			}
+			filter.Search = q
+			continue
+		}
+		if key == "availability" {
+			availability := Availability(query.Get(key))
+			switch availability {
+			case AvailabilityInStock, AvailabilityOutOfStock, AvailabilityAll:
+				filter.Availability = availability
+			default:
+				errs = append(errs, FieldError{Field: key, Message: fmt.Sprintf(
+					"availability has to be one of %s, %s, %s", AvailabilityInStock, AvailabilityOutOfStock, AvailabilityAll)})
+			}
+			continue
+		}
+		condition, err := parseCondition(key, query[key])
+		if err != nil {
+			errs = append(errs, FieldError{Field: key, Message: err.Error()})
+			continue
+		}
+		filter.Conditions = append(filter.Conditions, condition)
+	}
+	if len(errs) != 0 {
+		return BookFilter{}, errs
+	}
+	return filter, nil
+}
+
+func parseCondition(key string, rawValues []string) (Condition, error) {
+	match := filterKey.FindStringSubmatch(key)
+	if match == nil {
+		return Condition{}, fmt.Errorf("%s isn't a known filter", key)
+	}
+	field, ok := bookFilterFields[match[1]]
+	if !ok {
+		return Condition{}, fmt.Errorf("%s isn't a known filter", match[1])
+	}
+	condition := Condition{Field: match[1], Op: OpEq}
+	if match[2] != "" {
+		condition.Op = Operator(match[2])
+	}
+	if !field.supports(condition.Op) {
+		return Condition{}, fmt.Errorf("%s doesn't support the %s operator", condition.Field, condition.Op)
+	}
+
+	var values []string
+	for _, raw := range rawValues {
+		if field.list {
+			values = append(values, strings.Split(raw, ",")...)
+		} else {
+			values = append(values, raw)
+		}
+	}
+	if condition.Op != OpEq && len(values) > 1 {
+		return Condition{}, fmt.Errorf("%s takes a single value", key)
+	}
+	for _, raw := range values {
+		value, ok := field.parse(strings.TrimSpace(raw))
+		if !ok {
+			return Condition{}, fmt.Errorf("%s has to be %s", key, field.kind)
+		}
+		condition.Values = append(condition.Values, value)
+	}
+	return condition, nil
+}
+
+func (f filterField) supports(op Operator) bool {
+	for _, supported := range f.ops {
+		if op == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func parseText(s string) (interface{}, bool) {
+	return s, s != ""
+}
+
+func parseID(s string) (interface{}, bool) {
+	id, err := strconv.Atoi(s)
+	return id, err == nil && id > 0
+}
+
+func parseInteger(s string) (interface{}, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+func parseNumber(s string) (interface{}, bool) {
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil && !math.IsNaN(n) && !math.IsInf(n, 0)
+}
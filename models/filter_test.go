@@ -0,0 +1,101 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBookFilter(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          url.Values
+		expectedFilter BookFilter
+		expectedErrors ValidationErrors
+	}{
+		{
+			name:           "Empty",
+			query:          url.Values{},
+			expectedFilter: BookFilter{},
+		},
+		{
+			name: "All operators",
+			query: url.Values{
+				"name[contains]": {"hobbit"},
+				"genre":          {"1,3", "4"},
+				"price[gte]":     {"10"},
+				"price[lte]":     {"30.5"},
+				"amount[gt]":     {"0"},
+				"amount[lt]":     {"-1"},
+				"q":              {"  the hobbit "},
+				"availability":   {"all"},
+			},
+			expectedFilter: BookFilter{
+				Conditions: []Condition{
+					{Field: "amount", Op: OpGt, Values: []interface{}{0}},
+					{Field: "amount", Op: OpLt, Values: []interface{}{-1}},
+					{Field: "genre", Op: OpEq, Values: []interface{}{1, 3, 4}},
+					{Field: "name", Op: OpContains, Values: []interface{}{"hobbit"}},
+					{Field: "price", Op: OpGte, Values: []interface{}{10.0}},
+					{Field: "price", Op: OpLte, Values: []interface{}{30.5}},
+				},
+				Search:       "the hobbit",
+				Availability: AvailabilityAll,
+			},
+		},
+		{
+			name:  "Name isn't split on commas",
+			query: url.Values{"name": {"Yes, Minister"}},
+			expectedFilter: BookFilter{Conditions: []Condition{
+				{Field: "name", Op: OpEq, Values: []interface{}{"Yes, Minister"}},
+			}},
+		},
+		{
+			name:  "ISBNs are normalized",
+			query: url.Values{"isbn": {"0-306-40615-2,978-0-306-40615-7"}},
+			expectedFilter: BookFilter{Conditions: []Condition{
+				{Field: "isbn", Op: OpEq, Values: []interface{}{"9780306406157", "9780306406157"}},
+			}},
+		},
+		{
+			name: "Invalid",
+			query: url.Values{
+				"author":        {"0"},
+				"genre[gt]":     {"1"},
+				"isbn":          {"123"},
+				"name":          {" "},
+				"price":         {"NaN"},
+				"price[gte]":    {"1", "2"},
+				"q":             {""},
+				"amount[bogus]": {"1"},
+				"a-b":           {"1"},
+				"availability":  {"sold"},
+			},
+			expectedErrors: ValidationErrors{
+				{Field: "a-b", Message: "a-b isn't a known filter"},
+				{Field: "amount[bogus]", Message: "amount doesn't support the bogus operator"},
+				{Field: "author", Message: "author has to be a positive integer"},
+				{Field: "availability", Message: "availability has to be one of in_stock, out_of_stock, all"},
+				{Field: "genre[gt]", Message: "genre doesn't support the gt operator"},
+				{Field: "isbn", Message: "isbn has to be a valid ISBN-10 or ISBN-13"},
+				{Field: "name", Message: "name has to be non-empty text"},
+				{Field: "price", Message: "price has to be a number"},
+				{Field: "price[gte]", Message: "price[gte] takes a single value"},
+				{Field: "q", Message: "q has to have between 1 and 100 characters"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := ParseBookFilter(test.query)
+			if test.expectedErrors != nil {
+				assert.Equal(t, test.expectedErrors, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedFilter, filter)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"net/http"
+)
+
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type GenreList struct {
+	Genres []Genre `json:"genres"`
+}
+
+func (i *Genre) Bind(r *http.Request) error {
+	return Validate(i)
+}
+
+func (*Genre) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func (*GenreList) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+type CreateGenreResponse struct {
+	GenreID int `json:"id"`
+}
+
+func (CreateGenreResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
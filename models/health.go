@@ -0,0 +1,33 @@
+package models
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// Health is what GET /health reports.
+type Health struct {
+	Status   string          `json:"status"`
+	Database *DatabaseHealth `json:"database,omitempty"`
+}
+
+// DatabaseHealth is the state of the database connection as last checked.
+type DatabaseHealth struct {
+	Status string `json:"status"`
+	// Since is when Status last changed.
+	Since     time.Time `json:"since"`
+	CheckedAt time.Time `json:"checked_at"`
+	// Reconnects counts the times the connection came back after being lost.
+	Reconnects int `json:"reconnects"`
+	// Error is why the last check failed, while Status is down.
+	Error string `json:"error,omitempty"`
+}
+
+func (*Health) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
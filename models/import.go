@@ -0,0 +1,45 @@
+package models
+
+import (
+	"net/http"
+)
+
+const (
+	ImportCreated  = "created"
+	ImportUpdated  = "updated"
+	ImportRejected = "rejected"
+)
+
+// ImportRow reports what happened to one row of an imported catalog.
+type ImportRow struct {
+	Line    int          `json:"line"`
+	Status  string       `json:"status"`
+	ID      int          `json:"id,omitempty"`
+	Name    string       `json:"name,omitempty"`
+	Message string       `json:"message,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	Created  int         `json:"created"`
+	Updated  int         `json:"updated"`
+	Rejected int         `json:"rejected"`
+	Rows     []ImportRow `json:"rows"`
+}
+
+// Add records a row and counts it under its status.
+func (r *ImportReport) Add(row ImportRow) {
+	switch row.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportRejected:
+		r.Rejected++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+func (*ImportReport) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	errISBNLength   = errors.New("has to have 10 or 13 digits")
+	errISBNPrefix   = errors.New("has to start with 978 or 979")
+	errISBNChecksum = errors.New("has an invalid check digit")
+)
+
+func init() {
+	RegisterValidator("isbn", func(field reflect.Value, param string) error {
+		if field.Kind() != reflect.String || field.String() == "" {
+			return nil
+		}
+		_, err := NormalizeISBN(field.String())
+		return err
+	})
+}
+
+// NormalizeISBN checks an ISBN-10 or ISBN-13, written with or without
+// hyphens and spaces, and returns it as 13 bare digits.
+func NormalizeISBN(isbn string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(isbn))
+
+	switch len(digits) {
+	case 10:
+		// Weights run from 10 down to 1, and the check digit can be X for 10.
+		sum := 0
+		for i, r := range digits {
+			var value int
+			switch {
+			case r >= '0' && r <= '9':
+				value = int(r - '0')
+			case r == 'X' && i == 9:
+				value = 10
+			default:
+				return "", errISBNLength
+			}
+			sum += (10 - i) * value
+		}
+		if sum%11 != 0 {
+			return "", errISBNChecksum
+		}
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(rune('0'+ean13CheckDigit(isbn13))), nil
+	case 13:
+		for _, r := range digits {
+			if r < '0' || r > '9' {
+				return "", errISBNLength
+			}
+		}
+		if !strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979") {
+			return "", errISBNPrefix
+		}
+		if ean13CheckDigit(digits[:12]) != int(digits[12]-'0') {
+			return "", errISBNChecksum
+		}
+		return digits, nil
+	default:
+		return "", errISBNLength
+	}
+}
+
+// ean13CheckDigit returns the check digit of the first 12 digits of an
+// EAN-13, weighting them alternately by 1 and 3.
+func ean13CheckDigit(digits string) int {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(digits[i]-'0')
+	}
+	return (10 - sum%10) % 10
+}
+
+func parseISBN(s string) (interface{}, bool) {
+	isbn, err := NormalizeISBN(s)
+	return isbn, err == nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedISBN  string
+		expectedError string
+	}{
+		{name: "ISBN-13", input: "9780306406157", expectedISBN: "9780306406157"},
+		{name: "ISBN-13 with hyphens", input: "978-0-306-40615-7", expectedISBN: "9780306406157"},
+		{name: "ISBN-10", input: "0-306-40615-2", expectedISBN: "9780306406157"},
+		{name: "ISBN-10 with X check digit", input: "0-8044-2957-x", expectedISBN: "9780804429573"},
+		{name: "979 prefix", input: "979-10-90636-07-1", expectedISBN: "9791090636071"},
+		{name: "Wrong ISBN-10 check digit", input: "0306406153", expectedError: "has an invalid check digit"},
+		{name: "Wrong ISBN-13 check digit", input: "9780306406158", expectedError: "has an invalid check digit"},
+		{name: "Not a book", input: "4006381333931", expectedError: "has to start with 978 or 979"},
+		{name: "X in the middle", input: "03064X6152", expectedError: "has to have 10 or 13 digits"},
+		{name: "Too short", input: "12345", expectedError: "has to have 10 or 13 digits"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isbn, err := NormalizeISBN(test.input)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedISBN, isbn)
+		})
+	}
+}
+
+func TestBookBindISBN(t *testing.T) {
+	book := &Book{Name: "Seven Pillars", Genre: 1, ISBN: "0-306-40615-2"}
+	assert.NoError(t, book.Bind(nil))
+	assert.Equal(t, "9780306406157", book.ISBN)
+
+	book = &Book{Name: "Seven Pillars", Genre: 1, ISBN: "0-306-40615-3"}
+	assert.Equal(t, ValidationErrors{{Field: "isbn", Message: "isbn has an invalid check digit"}}, book.Bind(nil))
+}
@@ -1,32 +1,47 @@
 package models
 
 import (
-	"fmt"
 	"net/http"
+	"time"
 )
 
 type Book struct {
-	ID     int     `json:"id"`
-	Name   string  `json:"name" binding:"min=1,max=100"`
-	Genre  int     `json:"genre" binding:"min=1,max=3"`
-	Price  float64 `json:"price" binding:"min=0"`
-	Amount int     `json:"amount" binding:"min=0"`
+	ID   int    `json:"id"`
+	Name string `json:"name" binding:"required,max=100"`
+	// ISBN is optional and always stored as an ISBN-13 without hyphens.
+	ISBN    string          `json:"isbn,omitempty" binding:"isbn"`
+	Genre   int             `json:"genre" binding:"required"`
+	Price   float64         `json:"price" binding:"min=0"`
+	Amount  int             `json:"amount" binding:"min=0"`
+	Authors []AuthorSummary `json:"authors,omitempty"`
+	// Available is computed from Amount and ignored on writes.
+	Available bool `json:"available"`
+	// CreatedAt and UpdatedAt are set by the database and ignored on writes.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version is bumped on every update and sent to clients as the ETag.
+	Version int `json:"-"`
+	// ClearISBN makes an update with an empty ISBN remove the stored one,
+	// which it otherwise keeps, so clients unaware of ISBNs don't erase them.
+	ClearISBN bool `json:"-"`
+	// Rank and Highlight are only set on results of a search.
+	Rank      float64 `json:"rank,omitempty"`
+	Highlight string  `json:"highlight,omitempty"`
 }
 type BookList struct {
-	Books []Book `json:"books"`
+	Books      []Book `json:"books"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
 }
 
+// Bind validates the book and normalizes its ISBN.
 func (i *Book) Bind(r *http.Request) error {
-	if i.Name == "" {
-		return fmt.Errorf("name is a required field")
-	} else if len(i.Name) > 100 {
-		return fmt.Errorf("name has to be less than 100 characters")
-	} else if i.Price < 0 {
-		return fmt.Errorf("price can't be less than 0")
-	} else if i.Genre < 1 || i.Genre > 3 {
-		return fmt.Errorf("genre has to be between 1 and 3")
-	} else if i.Amount < 0 {
-		return fmt.Errorf("amount can't be less than 0")
+	if err := Validate(i); err != nil {
+		return err
+	}
+	if i.ISBN != "" {
+		// Validate has already checked the ISBN.
+		i.ISBN, _ = NormalizeISBN(i.ISBN)
 	}
 	return nil
 }
@@ -48,13 +63,10 @@ func (CreateBookResponse) Render(w http.ResponseWriter, r *http.Request) error {
 }
 
 type GetBooks struct {
-	Name  string `json:"name"`
-	Genre int    `json:"genre"`
+	Name  string `json:"name" binding:"max=100"`
+	Genre int    `json:"genre" binding:"min=0"`
 }
 
 func (i *GetBooks) Bind(r *http.Request) error {
-	if len(i.Name) > 100 {
-		return fmt.Errorf("name has to be less than 100 characters")
-	}
-	return nil
+	return Validate(i)
 }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Cursor points at the last book of a page. It is handed to clients as an
+// opaque string so the keyset it is built from can change without breaking them.
+type Cursor struct {
+	ID int `json:"id"`
+	// Keys holds the last book's values of the other fields the list is
+	// sorted by, and Sort the order they belong to.
+	Keys []interface{} `json:"keys,omitempty"`
+	Sort string        `json:"sort,omitempty"`
+}
+
+// Page describes which slice of a book list is requested and in which
+// order. A zero Cursor means the first page and an empty Sort the default
+// order.
+type Page struct {
+	Limit  int
+	Cursor Cursor
+	Sort   Sort
+}
+
+func (c Cursor) IsZero() bool {
+	return c.ID == 0
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	cursor := Cursor{}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID < 1 {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
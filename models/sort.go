@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortKey orders a book list by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Sort is the order of a book list, most significant key first.
+type Sort []SortKey
+
+var sortableBookFields = map[string]bool{
+	"id":         true,
+	"name":       true,
+	"genre":      true,
+	"price":      true,
+	"amount":     true,
+	"created_at": true,
+}
+
+// ParseSort reads a sort parameter like "price,-name", where a leading minus
+// sorts that field in descending order. An empty string means the default
+// order.
+func ParseSort(value string) (Sort, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var sort Sort
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		key := SortKey{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(key.Field, "-") {
+			key.Field, key.Desc = key.Field[1:], true
+		}
+		if !sortableBookFields[key.Field] {
+			return nil, ValidationErrors{{Field: "sort", Message: fmt.Sprintf("sort can't use %q", key.Field)}}
+		}
+		if seen[key.Field] {
+			return nil, ValidationErrors{{Field: "sort", Message: fmt.Sprintf("sort has %s more than once", key.Field)}}
+		}
+		seen[key.Field] = true
+		sort = append(sort, key)
+	}
+	return sort, nil
+}
+
+// String formats s the way ParseSort reads it.
+func (s Sort) String() string {
+	parts := make([]string, len(s))
+	for i, key := range s {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedSort  Sort
+		expectedError string
+	}{
+		{name: "Default", input: ""},
+		{
+			name:         "Several keys",
+			input:        "price,-name, id",
+			expectedSort: Sort{{Field: "price"}, {Field: "name", Desc: true}, {Field: "id"}},
+		},
+		{name: "Creation time", input: "-created_at", expectedSort: Sort{{Field: "created_at", Desc: true}}},
+		{name: "Unknown field", input: "price,-version", expectedError: `sort can't use "version"`},
+		{name: "Empty key", input: "price,", expectedError: `sort can't use ""`},
+		{name: "Repeated field", input: "price,-price", expectedError: "sort has price more than once"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sort, err := ParseSort(test.input)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedSort, sort)
+		})
+	}
+}
+
+func TestSortString(t *testing.T) {
+	assert.Equal(t, "price,-name", Sort{{Field: "price"}, {Field: "name", Desc: true}}.String())
+	assert.Equal(t, "", Sort(nil).String())
+}
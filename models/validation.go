@@ -0,0 +1,257 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError describes why a single field of a request body was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors is returned by Bind when one or more fields are invalid.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidatorFunc checks a single field against the parameter given in its
+// binding tag and returns an error describing the problem, if any. The
+// field name is prepended to the message by the caller.
+type ValidatorFunc func(field reflect.Value, param string) error
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFunc{}
+	patterns     sync.Map
+)
+
+// RegisterValidator makes a custom rule available to binding tags under
+// the given name, e.g. binding:"required,isbn".
+func RegisterValidator(name string, fn ValidatorFunc) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = fn
+}
+
+func lookupValidator(name string) (ValidatorFunc, bool) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	fn, ok := validators[name]
+	return fn, ok
+}
+
+type rule struct {
+	name  string
+	param string
+}
+
+// parseRules splits a binding tag into rules. Rules are separated by commas,
+// so a regex rule can't contain one.
+func parseRules(tag string) []rule {
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, param := part, ""
+		if i := strings.Index(part, "="); i >= 0 {
+			name, param = part[:i], part[i+1:]
+		}
+		rules = append(rules, rule{name: name, param: param})
+	}
+	return rules
+}
+
+// Validate checks every exported field of the struct v against the rules in
+// its binding tag and reports all failing fields at once. Fields are named
+// after their json tag. The supported rules are required, min, max, oneof,
+// regex and anything added with RegisterValidator. A rule that is none of
+// them, or has a malformed parameter, is a mistake in the code, not the
+// input, so Validate panics.
+func Validate(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		structField := structType.Field(i)
+		tag, ok := structField.Tag.Lookup("binding")
+		if !ok || structField.PkgPath != "" {
+			continue
+		}
+		name := fieldName(structField)
+		if err := validateField(name, value.Field(i), parseRules(tag)); err != nil {
+			errs = append(errs, FieldError{Field: name, Message: err.Error()})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func fieldName(structField reflect.StructField) string {
+	name := strings.Split(structField.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return structField.Name
+	}
+	return name
+}
+
+func validateField(name string, field reflect.Value, rules []rule) error {
+	params := map[string]string{}
+	for _, r := range rules {
+		params[r.name] = r.param
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			if _, ok := params["required"]; ok {
+				return fmt.Errorf("%s is a required field", name)
+			}
+			return nil
+		}
+		field = field.Elem()
+	}
+	for _, r := range rules {
+		var err error
+		switch r.name {
+		case "required":
+			if field.IsZero() {
+				err = fmt.Errorf("%s is a required field", name)
+			}
+		case "min", "max":
+			err = checkBounds(name, field, params)
+		case "oneof":
+			err = checkOneOf(name, field, r.param)
+		case "regex":
+			err = checkRegex(name, field, r.param)
+		default:
+			fn, ok := lookupValidator(r.name)
+			if !ok {
+				panic(fmt.Sprintf("models: %s has an unknown validation rule %q", name, r.name))
+			}
+			if fnErr := fn(field, r.param); fnErr != nil {
+				err = fmt.Errorf("%s %v", name, fnErr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBounds applies min and max together so that a field with both gets a
+// single "between" message. Strings, slices and maps are measured by length.
+func checkBounds(name string, field reflect.Value, params map[string]string) error {
+	minParam, hasMin := params["min"]
+	maxParam, hasMax := params["max"]
+	min := parseBound(name, "min", minParam, hasMin)
+	max := parseBound(name, "max", maxParam, hasMax)
+
+	var value float64
+	unit := ""
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = field.Float()
+	case reflect.String:
+		value = float64(utf8.RuneCountInString(field.String()))
+		unit = "characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		value = float64(field.Len())
+		unit = "items"
+	default:
+		panic(fmt.Sprintf("models: %s can't be checked against min or max", name))
+	}
+
+	tooSmall := hasMin && value < min
+	tooBig := hasMax && value > max
+	if !tooSmall && !tooBig {
+		return nil
+	}
+	switch {
+	case hasMin && hasMax && unit != "":
+		return fmt.Errorf("%s has to have between %s and %s %s", name, minParam, maxParam, unit)
+	case hasMin && hasMax:
+		return fmt.Errorf("%s has to be between %s and %s", name, minParam, maxParam)
+	case tooSmall && unit != "":
+		return fmt.Errorf("%s has to have at least %s %s", name, minParam, unit)
+	case tooSmall:
+		return fmt.Errorf("%s can't be less than %s", name, minParam)
+	case unit != "":
+		return fmt.Errorf("%s can't have more than %s %s", name, maxParam, unit)
+	default:
+		return fmt.Errorf("%s can't be greater than %s", name, maxParam)
+	}
+}
+
+// parseBound parses the parameter of a min or max rule. Like an unknown rule,
+// a bound that isn't a number is a mistake in the code.
+func parseBound(name, rule, param string, ok bool) float64 {
+	if !ok {
+		return 0
+	}
+	bound, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("models: %s has an invalid %s %q", name, rule, param))
+	}
+	return bound
+}
+
+// checkOneOf compares the field's string form with a space separated list of
+// allowed values, e.g. binding:"oneof=csv ndjson".
+func checkOneOf(name string, field reflect.Value, param string) error {
+	allowed := strings.Fields(param)
+	value := fmt.Sprint(field.Interface())
+	for _, candidate := range allowed {
+		if value == candidate {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has to be one of %s", name, strings.Join(allowed, ", "))
+}
+
+func checkRegex(name string, field reflect.Value, pattern string) error {
+	if field.Kind() != reflect.String {
+		panic(fmt.Sprintf("models: %s can't be matched against a pattern", name))
+	}
+	var re *regexp.Regexp
+	if cached, ok := patterns.Load(pattern); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			panic(fmt.Sprintf("models: %s has an invalid pattern %q", name, pattern))
+		}
+		patterns.Store(pattern, compiled)
+		re = compiled
+	}
+	if field.String() != "" && !re.MatchString(field.String()) {
+		return fmt.Errorf("%s has an invalid format", name)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validated struct {
+	Title  string   `json:"title" binding:"required,min=2,max=5"`
+	Rating float64  `json:"rating" binding:"min=1,max=5"`
+	Stock  int      `json:"stock" binding:"min=0"`
+	Format string   `json:"format" binding:"oneof=csv ndjson"`
+	Code   string   `json:"code" binding:"regex=^[A-Z]{3}$"`
+	Tags   []string `json:"tags" binding:"max=2"`
+	Slug   string   `json:"slug" binding:"lowercase"`
+	Note   *string  `json:"note" binding:"required"`
+	Hidden string
+}
+
+func TestValidate(t *testing.T) {
+	RegisterValidator("lowercase", func(field reflect.Value, param string) error {
+		if field.String() != strings.ToLower(field.String()) {
+			return fmt.Errorf("has to be lowercase")
+		}
+		return nil
+	})
+	note := "note"
+
+	tests := []struct {
+		name           string
+		input          validated
+		expectedErrors ValidationErrors
+	}{
+		{
+			name: "Ok",
+			input: validated{
+				Title: "abc", Rating: 3, Format: "csv", Code: "ABC", Tags: []string{"a"}, Slug: "abc", Note: &note,
+			},
+		},
+		{
+			name:  "All fields invalid",
+			input: validated{Rating: 7, Stock: -1, Format: "xml", Code: "abc", Tags: []string{"a", "b", "c"}, Slug: "ABC"},
+			expectedErrors: ValidationErrors{
+				{Field: "title", Message: "title is a required field"},
+				{Field: "rating", Message: "rating has to be between 1 and 5"},
+				{Field: "stock", Message: "stock can't be less than 0"},
+				{Field: "format", Message: "format has to be one of csv, ndjson"},
+				{Field: "code", Message: "code has an invalid format"},
+				{Field: "tags", Message: "tags can't have more than 2 items"},
+				{Field: "slug", Message: "slug has to be lowercase"},
+				{Field: "note", Message: "note is a required field"},
+			},
+		},
+		{
+			name:  "Length out of range",
+			input: validated{Title: "abcdefg", Rating: 1, Format: "ndjson", Note: &note},
+			expectedErrors: ValidationErrors{
+				{Field: "title", Message: "title has to have between 2 and 5 characters"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(&test.input)
+			if test.expectedErrors == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, test.expectedErrors, err)
+			}
+		})
+	}
+}
+
+func TestValidateMalformedRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         interface{}
+		expectedPanic string
+	}{
+		{
+			name: "Unknown rule",
+			input: &struct {
+				Name string `json:"name" binding:"requird"`
+			}{Name: "Emma"},
+			expectedPanic: `models: name has an unknown validation rule "requird"`,
+		},
+		{
+			name: "Malformed bound",
+			input: &struct {
+				Amount int `json:"amount" binding:"max=1OO"`
+			}{Amount: 5},
+			expectedPanic: `models: amount has an invalid max "1OO"`,
+		},
+		{
+			name: "Malformed pattern",
+			input: &struct {
+				Code string `json:"code" binding:"regex=^[A-Z+$"`
+			}{Code: "ABC"},
+			expectedPanic: `models: code has an invalid pattern "^[A-Z+$"`,
+		},
+		{
+			name: "Bound on a bool",
+			input: &struct {
+				Hidden bool `json:"hidden" binding:"min=1"`
+			}{},
+			expectedPanic: "models: hidden can't be checked against min or max",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.PanicsWithValue(t, test.expectedPanic, func() {
+				_ = Validate(test.input)
+			})
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package opds describes OPDS 1.2 catalog feeds, the Atom documents e-reader
+// apps browse catalogs with, and the OpenSearch description they search them
+// through. See https://specs.opds.io/opds-1.2.
+package opds
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const (
+	AtomType        = "application/atom+xml"
+	NavigationType  = AtomType + ";profile=opds-catalog;kind=navigation"
+	AcquisitionType = AtomType + ";profile=opds-catalog;kind=acquisition"
+	OpenSearchType  = "application/opensearchdescription+xml"
+
+	RelSelf        = "self"
+	RelStart       = "start"
+	RelUp          = "up"
+	RelFirst       = "first"
+	RelNext        = "next"
+	RelSearch      = "search"
+	RelSubsection  = "subsection"
+	RelAlternate   = "alternate"
+	RelAcquisition = "http://opds-spec.org/acquisition/buy"
+)
+
+// Feed is an Atom feed. A navigation feed's entries link to other feeds, an
+// acquisition feed's entries are books.
+type Feed struct {
+	XMLName xml.Name  `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string    `xml:"id"`
+	Title   string    `xml:"title"`
+	Updated time.Time `xml:"updated"`
+	Author  *Person   `xml:"author,omitempty"`
+	Links   []Link    `xml:"link"`
+	Entries []Entry   `xml:"entry"`
+}
+
+type Entry struct {
+	ID         string     `xml:"id"`
+	Title      string     `xml:"title"`
+	Updated    time.Time  `xml:"updated"`
+	Published  *time.Time `xml:"published,omitempty"`
+	Authors    []Person   `xml:"author,omitempty"`
+	Categories []Category `xml:"category,omitempty"`
+	Content    *Content   `xml:"content,omitempty"`
+	Links      []Link     `xml:"link"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+}
+
+type Category struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type Content struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type Link struct {
+	Rel   string `xml:"rel,attr"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+// OpenSearchDescription tells clients how to build a search URL. Template
+// holds {searchTerms} where the search text goes.
+type OpenSearchDescription struct {
+	XMLName     xml.Name `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName   string   `xml:"ShortName"`
+	Description string   `xml:"Description"`
+	URL         URL      `xml:"Url"`
+}
+
+type URL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// TextContent returns plain text content, or nil for empty text.
+func TextContent(text string) *Content {
+	if text == "" {
+		return nil
+	}
+	return &Content{Type: "text", Text: text}
+}
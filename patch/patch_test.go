@@ -0,0 +1,135 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		doc           string
+		patch         string
+		expectedDoc   string
+		expectedError error
+	}{
+		{
+			name:        "Replace field",
+			doc:         `{"name":"hello","amount":1}`,
+			patch:       `{"amount":5}`,
+			expectedDoc: `{"amount":5,"name":"hello"}`,
+		},
+		{
+			name:        "Remove field",
+			doc:         `{"name":"hello","amount":1}`,
+			patch:       `{"name":null}`,
+			expectedDoc: `{"amount":1}`,
+		},
+		{
+			name:        "Nested objects and arrays",
+			doc:         `{"a":{"b":1,"c":2},"list":[1,2]}`,
+			patch:       `{"a":{"b":null,"d":3},"list":[3]}`,
+			expectedDoc: `{"a":{"c":2,"d":3},"list":[3]}`,
+		},
+		{
+			name:          "Malformed patch",
+			doc:           `{}`,
+			patch:         `{"a":`,
+			expectedError: ErrMalformed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := MergePatch([]byte(test.doc), []byte(test.patch))
+			if test.expectedError != nil {
+				assert.True(t, errors.Is(err, test.expectedError))
+			} else {
+				assert.NoError(t, err)
+				assert.JSONEq(t, test.expectedDoc, string(result))
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name          string
+		doc           string
+		patch         string
+		expectedDoc   string
+		expectError   bool
+		expectedError error
+	}{
+		{
+			name:        "Replace and add",
+			doc:         `{"name":"hello","amount":1}`,
+			patch:       `[{"op":"replace","path":"/amount","value":3},{"op":"add","path":"/price","value":1.5}]`,
+			expectedDoc: `{"name":"hello","amount":3,"price":1.5}`,
+		},
+		{
+			name:        "Array operations",
+			doc:         `{"authors":[{"id":1},{"id":2}]}`,
+			patch:       `[{"op":"add","path":"/authors/-","value":{"id":3}},{"op":"remove","path":"/authors/0"},{"op":"add","path":"/authors/0","value":{"id":4}}]`,
+			expectedDoc: `{"authors":[{"id":4},{"id":2},{"id":3}]}`,
+		},
+		{
+			name:        "Move and copy",
+			doc:         `{"a":{"b":1},"c":2}`,
+			patch:       `[{"op":"move","from":"/a/b","path":"/d"},{"op":"copy","from":"/c","path":"/a/e"}]`,
+			expectedDoc: `{"a":{"e":2},"c":2,"d":1}`,
+		},
+		{
+			name:        "Escaped pointer",
+			doc:         `{"a/b":1,"m~n":2}`,
+			patch:       `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`,
+			expectedDoc: `{"a/b":3}`,
+		},
+		{
+			name:        "Test passes",
+			doc:         `{"price":1.50}`,
+			patch:       `[{"op":"test","path":"/price","value":1.5}]`,
+			expectedDoc: `{"price":1.50}`,
+		},
+		{
+			name:        "Test fails",
+			doc:         `{"price":1.5}`,
+			patch:       `[{"op":"test","path":"/price","value":2}]`,
+			expectError: true,
+		},
+		{
+			name:        "Replace missing path",
+			doc:         `{}`,
+			patch:       `[{"op":"replace","path":"/amount","value":3}]`,
+			expectError: true,
+		},
+		{
+			name:          "Unknown operation",
+			doc:           `{}`,
+			patch:         `[{"op":"merge","path":"/amount"}]`,
+			expectedError: ErrMalformed,
+		},
+		{
+			name:          "Not an array",
+			doc:           `{}`,
+			patch:         `{"op":"add"}`,
+			expectedError: ErrMalformed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Apply([]byte(test.doc), []byte(test.patch))
+			switch {
+			case test.expectedError != nil:
+				assert.True(t, errors.Is(err, test.expectedError))
+			case test.expectError:
+				assert.Error(t, err)
+				assert.False(t, errors.Is(err, ErrMalformed))
+			default:
+				assert.NoError(t, err)
+				assert.JSONEq(t, test.expectedDoc, string(result))
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Package rss describes RSS 2.0 documents. See
+// https://www.rssboard.org/rss-specification.
+package rss
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const ContentType = "application/rss+xml"
+
+type RSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel Channel  `xml:"channel"`
+}
+
+type Channel struct {
+	Title         string `xml:"title"`
+	Link          string `xml:"link"`
+	Description   string `xml:"description"`
+	LastBuildDate Date   `xml:"lastBuildDate"`
+	Items         []Item `xml:"item"`
+}
+
+type Item struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description,omitempty"`
+	Category    string `xml:"category,omitempty"`
+	GUID        GUID   `xml:"guid"`
+	PubDate     Date   `xml:"pubDate"`
+}
+
+type GUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// Date is a time written in the RFC 822 format RSS uses.
+type Date time.Time
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(time.Time(d).Format(time.RFC1123Z)), nil
+}
+
+// New returns an RSS 2.0 document with a single channel.
+func New(channel Channel) RSS {
+	return RSS{Version: "2.0", Channel: channel}
+}
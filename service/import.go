@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GlobantObrikosina/golang-rest-api/catalog"
+	"github.com/GlobantObrikosina/golang-rest-api/db"
+	"github.com/GlobantObrikosina/golang-rest-api/models"
+	"io"
+	"strings"
+)
+
+// ImportBooks upserts every valid record of a catalog by name and reports
+// each row as created, updated or rejected. Rows are validated with
+// Book.Bind and genres are matched by name, ignoring case. Rows are written
+// one by one, so an error that isn't about a single row stops the import
+// after the rows already written and is returned with the report so far.
+func (s *BooksManagerService) ImportBooks(ctx context.Context, reader catalog.Reader) (*models.ImportReport, error) {
+	genres, err := s.repo.GetAllGenres(ctx)
+	if err != nil {
+		return nil, err
+	}
+	genreIDs := map[string]int{}
+	for _, genre := range genres.Genres {
+		genreIDs[strings.ToLower(genre.Name)] = genre.ID
+	}
+
+	report := &models.ImportReport{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+		row, err := s.importRecord(ctx, record, genreIDs)
+		if err != nil {
+			return report, err
+		}
+		report.Add(row)
+	}
+}
+
+func (s *BooksManagerService) importRecord(ctx context.Context, record catalog.Record, genreIDs map[string]int) (models.ImportRow, error) {
+	row := models.ImportRow{Line: record.Line, Name: record.Book.Name, Status: models.ImportRejected}
+	if record.Err != nil {
+		return rejectRow(row, record.Err), nil
+	}
+	book := record.Book
+	if record.Genre != "" {
+		id, ok := genreIDs[strings.ToLower(record.Genre)]
+		if !ok {
+			return rejectRow(row, models.ValidationErrors{
+				{Field: "genre", Message: fmt.Sprintf("genre %q doesn't exist", record.Genre)},
+			}), nil
+		}
+		book.Genre = id
+	}
+	// Book.Bind only validates the book and doesn't look at the request.
+	if err := book.Bind(nil); err != nil {
+		return rejectRow(row, err), nil
+	}
+
+	created, err := s.repo.UpsertBook(ctx, &book)
+	var (
+		conflict   *db.ConflictError
+		validation *db.ValidationError
+		notFound   *db.NotFoundError
+	)
+	if errors.As(err, &conflict) || errors.As(err, &validation) || errors.As(err, &notFound) {
+		return rejectRow(row, err), nil
+	}
+	if err != nil {
+		return row, err
+	}
+	row.ID = book.ID
+	row.Status = models.ImportUpdated
+	if created {
+		row.Status = models.ImportCreated
+	}
+	return row, nil
+}
+
+func rejectRow(row models.ImportRow, err error) models.ImportRow {
+	row.Status = models.ImportRejected
+	row.Message = err.Error()
+	var fieldErrs models.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		row.Errors = fieldErrs
+	}
+	return row
+}
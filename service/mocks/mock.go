@@ -5,6 +5,7 @@
 package mock_service
 
 import (
+	context "context"
 	reflect "reflect"
 
 	models "github.com/GlobantObrikosina/golang-rest-api/models"
@@ -34,6 +35,21 @@ func (m *MockDatabaseBooksManager) EXPECT() *MockDatabaseBooksManagerMockRecorde
 	return m.recorder
 }
 
+// BatchBooks mocks base method.
+func (m *MockDatabaseBooksManager) BatchBooks(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchBooks", ctx, operations, atomic)
+	ret0, _ := ret[0].([]models.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchBooks indicates an expected call of BatchBooks.
+func (mr *MockDatabaseBooksManagerMockRecorder) BatchBooks(ctx, operations, atomic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchBooks", reflect.TypeOf((*MockDatabaseBooksManager)(nil).BatchBooks), ctx, operations, atomic)
+}
+
 // Close mocks base method.
 func (m *MockDatabaseBooksManager) Close() error {
 	m.ctrl.T.Helper()
@@ -48,76 +64,282 @@ func (mr *MockDatabaseBooksManagerMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockDatabaseBooksManager)(nil).Close))
 }
 
+// CreateAuthor mocks base method.
+func (m *MockDatabaseBooksManager) CreateAuthor(ctx context.Context, author *models.Author) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthor", ctx, author)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuthor indicates an expected call of CreateAuthor.
+func (mr *MockDatabaseBooksManagerMockRecorder) CreateAuthor(ctx, author interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthor", reflect.TypeOf((*MockDatabaseBooksManager)(nil).CreateAuthor), ctx, author)
+}
+
 // CreateBook mocks base method.
-func (m *MockDatabaseBooksManager) CreateBook(book *models.Book) (int, error) {
+func (m *MockDatabaseBooksManager) CreateBook(ctx context.Context, book *models.Book) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateBook", book)
+	ret := m.ctrl.Call(m, "CreateBook", ctx, book)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateBook indicates an expected call of CreateBook.
-func (mr *MockDatabaseBooksManagerMockRecorder) CreateBook(book interface{}) *gomock.Call {
+func (mr *MockDatabaseBooksManagerMockRecorder) CreateBook(ctx, book interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBook", reflect.TypeOf((*MockDatabaseBooksManager)(nil).CreateBook), ctx, book)
+}
+
+// CreateGenre mocks base method.
+func (m *MockDatabaseBooksManager) CreateGenre(ctx context.Context, genre *models.Genre) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGenre", ctx, genre)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGenre indicates an expected call of CreateGenre.
+func (mr *MockDatabaseBooksManagerMockRecorder) CreateGenre(ctx, genre interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBook", reflect.TypeOf((*MockDatabaseBooksManager)(nil).CreateBook), book)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGenre", reflect.TypeOf((*MockDatabaseBooksManager)(nil).CreateGenre), ctx, genre)
+}
+
+// DeleteAuthorByID mocks base method.
+func (m *MockDatabaseBooksManager) DeleteAuthorByID(ctx context.Context, authorId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthorByID", ctx, authorId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthorByID indicates an expected call of DeleteAuthorByID.
+func (mr *MockDatabaseBooksManagerMockRecorder) DeleteAuthorByID(ctx, authorId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthorByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).DeleteAuthorByID), ctx, authorId)
 }
 
 // DeleteBookByID mocks base method.
-func (m *MockDatabaseBooksManager) DeleteBookByID(bookId int) error {
+func (m *MockDatabaseBooksManager) DeleteBookByID(ctx context.Context, bookId, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteBookByID", bookId)
+	ret := m.ctrl.Call(m, "DeleteBookByID", ctx, bookId, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteBookByID indicates an expected call of DeleteBookByID.
-func (mr *MockDatabaseBooksManagerMockRecorder) DeleteBookByID(bookId interface{}) *gomock.Call {
+func (mr *MockDatabaseBooksManagerMockRecorder) DeleteBookByID(ctx, bookId, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBookByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).DeleteBookByID), ctx, bookId, version)
+}
+
+// DeleteGenreByID mocks base method.
+func (m *MockDatabaseBooksManager) DeleteGenreByID(ctx context.Context, genreId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGenreByID", ctx, genreId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGenreByID indicates an expected call of DeleteGenreByID.
+func (mr *MockDatabaseBooksManagerMockRecorder) DeleteGenreByID(ctx, genreId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBookByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).DeleteBookByID), bookId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGenreByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).DeleteGenreByID), ctx, genreId)
+}
+
+// ExportBooks mocks base method.
+func (m *MockDatabaseBooksManager) ExportBooks(ctx context.Context, filter models.BookFilter, fn func(models.Book, string) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportBooks", ctx, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportBooks indicates an expected call of ExportBooks.
+func (mr *MockDatabaseBooksManagerMockRecorder) ExportBooks(ctx, filter, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportBooks", reflect.TypeOf((*MockDatabaseBooksManager)(nil).ExportBooks), ctx, filter, fn)
+}
+
+// GetAllAuthors mocks base method.
+func (m *MockDatabaseBooksManager) GetAllAuthors(ctx context.Context) (*models.AuthorList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllAuthors", ctx)
+	ret0, _ := ret[0].(*models.AuthorList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllAuthors indicates an expected call of GetAllAuthors.
+func (mr *MockDatabaseBooksManagerMockRecorder) GetAllAuthors(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllAuthors", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetAllAuthors), ctx)
 }
 
 // GetAllBooks mocks base method.
-func (m *MockDatabaseBooksManager) GetAllBooks(filterCondition map[string][]string) (*models.BookList, error) {
+func (m *MockDatabaseBooksManager) GetAllBooks(ctx context.Context, filter models.BookFilter, page models.Page) (*models.BookList, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllBooks", filterCondition)
+	ret := m.ctrl.Call(m, "GetAllBooks", ctx, filter, page)
 	ret0, _ := ret[0].(*models.BookList)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllBooks indicates an expected call of GetAllBooks.
-func (mr *MockDatabaseBooksManagerMockRecorder) GetAllBooks(filterCondition interface{}) *gomock.Call {
+func (mr *MockDatabaseBooksManagerMockRecorder) GetAllBooks(ctx, filter, page interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllBooks", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetAllBooks), filterCondition)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllBooks", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetAllBooks), ctx, filter, page)
+}
+
+// GetAllGenres mocks base method.
+func (m *MockDatabaseBooksManager) GetAllGenres(ctx context.Context) (*models.GenreList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllGenres", ctx)
+	ret0, _ := ret[0].(*models.GenreList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllGenres indicates an expected call of GetAllGenres.
+func (mr *MockDatabaseBooksManagerMockRecorder) GetAllGenres(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllGenres", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetAllGenres), ctx)
+}
+
+// GetAuthorByID mocks base method.
+func (m *MockDatabaseBooksManager) GetAuthorByID(ctx context.Context, authorId int) (models.Author, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthorByID", ctx, authorId)
+	ret0, _ := ret[0].(models.Author)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthorByID indicates an expected call of GetAuthorByID.
+func (mr *MockDatabaseBooksManagerMockRecorder) GetAuthorByID(ctx, authorId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthorByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetAuthorByID), ctx, authorId)
 }
 
 // GetBookByID mocks base method.
-func (m *MockDatabaseBooksManager) GetBookByID(bookId int) (models.Book, error) {
+func (m *MockDatabaseBooksManager) GetBookByID(ctx context.Context, bookId int) (models.Book, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBookByID", bookId)
+	ret := m.ctrl.Call(m, "GetBookByID", ctx, bookId)
 	ret0, _ := ret[0].(models.Book)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBookByID indicates an expected call of GetBookByID.
-func (mr *MockDatabaseBooksManagerMockRecorder) GetBookByID(bookId interface{}) *gomock.Call {
+func (mr *MockDatabaseBooksManagerMockRecorder) GetBookByID(ctx, bookId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetBookByID), ctx, bookId)
+}
+
+// GetBookByISBN mocks base method.
+func (m *MockDatabaseBooksManager) GetBookByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookByISBN", ctx, isbn)
+	ret0, _ := ret[0].(models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookByISBN indicates an expected call of GetBookByISBN.
+func (mr *MockDatabaseBooksManagerMockRecorder) GetBookByISBN(ctx, isbn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookByISBN", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetBookByISBN), ctx, isbn)
+}
+
+// GetGenreByID mocks base method.
+func (m *MockDatabaseBooksManager) GetGenreByID(ctx context.Context, genreId int) (models.Genre, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGenreByID", ctx, genreId)
+	ret0, _ := ret[0].(models.Genre)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGenreByID indicates an expected call of GetGenreByID.
+func (mr *MockDatabaseBooksManagerMockRecorder) GetGenreByID(ctx, genreId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGenreByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetGenreByID), ctx, genreId)
+}
+
+// Ping mocks base method.
+func (m *MockDatabaseBooksManager) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockDatabaseBooksManagerMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockDatabaseBooksManager)(nil).Ping), ctx)
+}
+
+// UpdateAuthorByID mocks base method.
+func (m *MockDatabaseBooksManager) UpdateAuthorByID(ctx context.Context, authorId int, authorData models.Author) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAuthorByID", ctx, authorId, authorData)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAuthorByID indicates an expected call of UpdateAuthorByID.
+func (mr *MockDatabaseBooksManagerMockRecorder) UpdateAuthorByID(ctx, authorId, authorData interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).GetBookByID), bookId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAuthorByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).UpdateAuthorByID), ctx, authorId, authorData)
 }
 
 // UpdateBookByID mocks base method.
-func (m *MockDatabaseBooksManager) UpdateBookByID(bookId int, bookData models.Book) (int, error) {
+func (m *MockDatabaseBooksManager) UpdateBookByID(ctx context.Context, bookId int, bookData models.Book) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateBookByID", bookId, bookData)
+	ret := m.ctrl.Call(m, "UpdateBookByID", ctx, bookId, bookData)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateBookByID indicates an expected call of UpdateBookByID.
-func (mr *MockDatabaseBooksManagerMockRecorder) UpdateBookByID(bookId, bookData interface{}) *gomock.Call {
+func (mr *MockDatabaseBooksManagerMockRecorder) UpdateBookByID(ctx, bookId, bookData interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBookByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).UpdateBookByID), ctx, bookId, bookData)
+}
+
+// UpdateGenreByID mocks base method.
+func (m *MockDatabaseBooksManager) UpdateGenreByID(ctx context.Context, genreId int, genreData models.Genre) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateGenreByID", ctx, genreId, genreData)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateGenreByID indicates an expected call of UpdateGenreByID.
+func (mr *MockDatabaseBooksManagerMockRecorder) UpdateGenreByID(ctx, genreId, genreData interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGenreByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).UpdateGenreByID), ctx, genreId, genreData)
+}
+
+// UpsertBook mocks base method.
+func (m *MockDatabaseBooksManager) UpsertBook(ctx context.Context, book *models.Book) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertBook", ctx, book)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertBook indicates an expected call of UpsertBook.
+func (mr *MockDatabaseBooksManagerMockRecorder) UpsertBook(ctx, book interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBookByID", reflect.TypeOf((*MockDatabaseBooksManager)(nil).UpdateBookByID), bookId, bookData)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertBook", reflect.TypeOf((*MockDatabaseBooksManager)(nil).UpsertBook), ctx, book)
 }
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"github.com/GlobantObrikosina/golang-rest-api/db"
 	"github.com/GlobantObrikosina/golang-rest-api/models"
 )
@@ -8,11 +10,26 @@ import (
 //go:generate mockgen -source=./service.go -destination=./mocks/mock.go
 
 type DatabaseBooksManager interface {
-	GetAllBooks(filterCondition map[string][]string) (*models.BookList, error)
-	CreateBook(book *models.Book) (int, error)
-	GetBookByID(bookId int) (models.Book, error)
-	DeleteBookByID(bookId int) error
-	UpdateBookByID(bookId int, bookData models.Book) (int, error)
+	GetAllBooks(ctx context.Context, filter models.BookFilter, page models.Page) (*models.BookList, error)
+	CreateBook(ctx context.Context, book *models.Book) (int, error)
+	GetBookByID(ctx context.Context, bookId int) (models.Book, error)
+	GetBookByISBN(ctx context.Context, isbn string) (models.Book, error)
+	DeleteBookByID(ctx context.Context, bookId int, version int) error
+	UpdateBookByID(ctx context.Context, bookId int, bookData models.Book) (int, error)
+	BatchBooks(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error)
+	UpsertBook(ctx context.Context, book *models.Book) (bool, error)
+	ExportBooks(ctx context.Context, filter models.BookFilter, fn func(book models.Book, genre string) error) error
+	GetAllGenres(ctx context.Context) (*models.GenreList, error)
+	GetGenreByID(ctx context.Context, genreId int) (models.Genre, error)
+	CreateGenre(ctx context.Context, genre *models.Genre) (int, error)
+	UpdateGenreByID(ctx context.Context, genreId int, genreData models.Genre) (int, error)
+	DeleteGenreByID(ctx context.Context, genreId int) error
+	GetAllAuthors(ctx context.Context) (*models.AuthorList, error)
+	GetAuthorByID(ctx context.Context, authorId int) (models.Author, error)
+	CreateAuthor(ctx context.Context, author *models.Author) (int, error)
+	UpdateAuthorByID(ctx context.Context, authorId int, authorData models.Author) (int, error)
+	DeleteAuthorByID(ctx context.Context, authorId int) error
+	Ping(ctx context.Context) error
 	Close() error
 }
 
@@ -24,22 +41,143 @@ func NewService(repo db.DatabaseBooksManager) *BooksManagerService {
 	return &BooksManagerService{repo: repo}
 }
 
-func (s *BooksManagerService) CreateBook(book *models.Book) (int, error) {
-	return s.repo.CreateBook(book)
+func (s *BooksManagerService) CreateBook(ctx context.Context, book *models.Book) (int, error) {
+	if err := s.checkGenre(ctx, book.Genre); err != nil {
+		return 0, err
+	}
+	return s.repo.CreateBook(ctx, book)
 }
 
-func (s *BooksManagerService) GetBookByID(id int) (models.Book, error) {
-	return s.repo.GetBookByID(id)
+func (s *BooksManagerService) GetBookByID(ctx context.Context, id int) (models.Book, error) {
+	return s.repo.GetBookByID(ctx, id)
 }
 
-func (s *BooksManagerService) GetAllBooks(filterCondition map[string][]string) (*models.BookList, error) {
-	return s.repo.GetAllBooks(filterCondition)
+func (s *BooksManagerService) GetBookByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	return s.repo.GetBookByISBN(ctx, isbn)
 }
 
-func (s *BooksManagerService) DeleteBookByID(id int) error {
-	return s.repo.DeleteBookByID(id)
+func (s *BooksManagerService) GetAllBooks(ctx context.Context, filter models.BookFilter, page models.Page) (*models.BookList, error) {
+	return s.repo.GetAllBooks(ctx, filter, page)
 }
 
-func (s *BooksManagerService) UpdateBookByID(id int, book models.Book) (int, error) {
-	return s.repo.UpdateBookByID(id, book)
+func (s *BooksManagerService) DeleteBookByID(ctx context.Context, id int, version int) error {
+	return s.repo.DeleteBookByID(ctx, id, version)
+}
+
+// UpdateBookByID checks the genre before updating. A missing book is
+// reported before a missing genre, as it would be without the check.
+func (s *BooksManagerService) UpdateBookByID(ctx context.Context, id int, book models.Book) (int, error) {
+	err := s.checkGenre(ctx, book.Genre)
+	var fieldErrors models.ValidationErrors
+	if errors.As(err, &fieldErrors) {
+		if _, err := s.repo.GetBookByID(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.UpdateBookByID(ctx, id, book)
+}
+
+func (s *BooksManagerService) ExportBooks(ctx context.Context, filter models.BookFilter, fn func(book models.Book, genre string) error) error {
+	return s.repo.ExportBooks(ctx, filter, fn)
+}
+
+// BatchBooks checks the genres of the books with a single lookup, reporting
+// a missing one with the same field error as CreateBook and UpdateBookByID.
+// The operations failing that check aren't run, and an atomic batch with
+// any of them isn't run at all.
+func (s *BooksManagerService) BatchBooks(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(operations))
+	var valid []models.BatchOperation
+	var validIndexes []int
+	var genres map[int]bool
+	for i, operation := range operations {
+		results[i] = models.BatchResult{Op: operation.Op, ID: operation.ID}
+		if operation.Op != models.BatchDelete && operation.Book != nil {
+			if genres == nil {
+				list, err := s.repo.GetAllGenres(ctx)
+				if err != nil {
+					return results, err
+				}
+				genres = make(map[int]bool, len(list.Genres))
+				for _, genre := range list.Genres {
+					genres[genre.ID] = true
+				}
+			}
+			if !genres[operation.Book.Genre] {
+				results[i].Err = errMissingGenre
+				continue
+			}
+		}
+		valid = append(valid, operation)
+		validIndexes = append(validIndexes, i)
+	}
+	if len(valid) == len(operations) {
+		return s.repo.BatchBooks(ctx, operations, atomic)
+	}
+	if len(valid) == 0 || atomic {
+		return results, nil
+	}
+	ran, err := s.repo.BatchBooks(ctx, valid, atomic)
+	for j, i := range validIndexes {
+		if j < len(ran) {
+			results[i] = ran[j]
+		}
+	}
+	return results, err
+}
+
+var errMissingGenre = models.ValidationErrors{{Field: "genre", Message: "genre doesn't exist"}}
+
+// checkGenre reports a missing genre as a field error, the same way Bind
+// reports any other invalid field of a book.
+func (s *BooksManagerService) checkGenre(ctx context.Context, genreID int) error {
+	_, err := s.repo.GetGenreByID(ctx, genreID)
+	var notFound *db.NotFoundError
+	if errors.As(err, &notFound) {
+		return errMissingGenre
+	}
+	return err
+}
+
+func (s *BooksManagerService) GetAllGenres(ctx context.Context) (*models.GenreList, error) {
+	return s.repo.GetAllGenres(ctx)
+}
+
+func (s *BooksManagerService) GetGenreByID(ctx context.Context, id int) (models.Genre, error) {
+	return s.repo.GetGenreByID(ctx, id)
+}
+
+func (s *BooksManagerService) CreateGenre(ctx context.Context, genre *models.Genre) (int, error) {
+	return s.repo.CreateGenre(ctx, genre)
+}
+
+func (s *BooksManagerService) UpdateGenreByID(ctx context.Context, id int, genre models.Genre) (int, error) {
+	return s.repo.UpdateGenreByID(ctx, id, genre)
+}
+
+func (s *BooksManagerService) DeleteGenreByID(ctx context.Context, id int) error {
+	return s.repo.DeleteGenreByID(ctx, id)
+}
+
+func (s *BooksManagerService) GetAllAuthors(ctx context.Context) (*models.AuthorList, error) {
+	return s.repo.GetAllAuthors(ctx)
+}
+
+func (s *BooksManagerService) GetAuthorByID(ctx context.Context, id int) (models.Author, error) {
+	return s.repo.GetAuthorByID(ctx, id)
+}
+
+func (s *BooksManagerService) CreateAuthor(ctx context.Context, author *models.Author) (int, error) {
+	return s.repo.CreateAuthor(ctx, author)
+}
+
+func (s *BooksManagerService) UpdateAuthorByID(ctx context.Context, id int, author models.Author) (int, error) {
+	return s.repo.UpdateAuthorByID(ctx, id, author)
+}
+
+func (s *BooksManagerService) DeleteAuthorByID(ctx context.Context, id int) error {
+	return s.repo.DeleteAuthorByID(ctx, id)
 }